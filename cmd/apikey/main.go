@@ -0,0 +1,143 @@
+// Command apikey manages the API keys of the logger service. It reads the
+// MongoDB settings the same way the service does, from LOGGER_CONFIG and
+// LOGGER_* environment variables.
+//
+//	apikey create -org acme [-projects 7,8] [-expires 720h] [-name dashboards]
+//	apikey revoke -id 3f2a9c0e1b7d4a55
+//	apikey list [-org acme]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bondzai/logger/internal/auth"
+	"github.com/bondzai/logger/internal/config"
+	"github.com/bondzai/logger/internal/mongodb"
+)
+
+const timeout = 30 * time.Second
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	mongo := mongodb.NewMongoDB()
+	if err := mongo.Connect(cfg.Mongo.URL, cfg.Mongo.Database); err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer mongo.CloseMongoDB()
+
+	keys := mongodb.NewAPIKeyStore(mongo, cfg.Mongo.APIKeys)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "create":
+		err = create(ctx, keys, args)
+	case "revoke":
+		err = revoke(ctx, keys, args)
+	case "list":
+		err = list(ctx, keys, args)
+	default:
+		usage()
+	}
+	if err != nil {
+		mongo.CloseMongoDB()
+		log.Fatalf("Failed to %s API key: %v", command, err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey create|revoke|list [flags]")
+	os.Exit(2)
+}
+
+func create(ctx context.Context, keys *mongodb.APIKeyStore, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	organization := flags.String("org", "", "organization the key is bound to (required)")
+	projectList := flags.String("projects", "", "comma-separated project ids the key is restricted to")
+	expires := flags.Duration("expires", 0, "how long the key is valid, 0 for no expiry")
+	name := flags.String("name", "", "description of the key")
+	flags.Parse(args)
+
+	if *organization == "" {
+		return fmt.Errorf("-org is required")
+	}
+
+	var projects []int64
+	for _, field := range strings.Split(*projectList, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		project, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid project id %q", field)
+		}
+		projects = append(projects, project)
+	}
+
+	key, token, err := auth.NewKey(*name, *organization, projects, *expires)
+	if err != nil {
+		return err
+	}
+	if err := keys.Create(ctx, key); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Created key %s; the token below is not stored and cannot be shown again.\n", key.ID)
+	fmt.Println(token)
+	return nil
+}
+
+func revoke(ctx context.Context, keys *mongodb.APIKeyStore, args []string) error {
+	flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+	id := flags.String("id", "", "id of the key (required)")
+	flags.Parse(args)
+
+	if *id == "" {
+		return fmt.Errorf("-id is required")
+	}
+	return keys.Revoke(ctx, *id)
+}
+
+func list(ctx context.Context, keys *mongodb.APIKeyStore, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	organization := flags.String("org", "", "only list keys of this organization")
+	flags.Parse(args)
+
+	found, err := keys.List(ctx, *organization)
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "ID\tORGANIZATION\tPROJECTS\tNAME\tCREATED\tSTATUS")
+	for _, key := range found {
+		state := "active"
+		if err := key.Usable(time.Now()); err != nil {
+			state = err.Error()
+		} else if !key.ExpiresAt.IsZero() {
+			state = "expires " + key.ExpiresAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(out, "%s\t%s\t%v\t%s\t%s\t%s\n", key.ID, key.Organization, key.Projects, key.Name, key.CreatedAt.Format(time.RFC3339), state)
+	}
+	return out.Flush()
+}
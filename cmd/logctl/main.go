@@ -0,0 +1,374 @@
+// Command logctl is a command-line client of the logger service. Connection
+// settings and credentials come from a profile file, see Profile, and can be
+// overridden by flags on every command.
+//
+//	logctl query [-organization acme] [-project-id 7] [-from 24h] [-type CRON] [-limit 100] [-o table|json]
+//	logctl tail [-replay 20] [-task 3,4] [-o table|json]
+//	logctl export [-format ndjson|csv] [-gzip] [-out logs.csv.gz] [-from 2024-01-01T00:00:00Z]
+//	logctl health
+//
+// Run a command with -h for all of its flags.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bondzai/logger/internal/export"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "query":
+		err = query(ctx, args)
+	case "tail":
+		err = tail(ctx, args)
+	case "export":
+		err = exportLogs(ctx, args)
+	case "health":
+		err = health(ctx, args)
+	default:
+		usage()
+	}
+	if err != nil {
+		stop()
+		log.Fatalf("%s failed: %v", command, err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: logctl query|tail|export|health [flags]")
+	os.Exit(2)
+}
+
+func query(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	conn := addConnectionFlags(flags)
+	filter := addFilterFlags(flags)
+	limit := flags.Int("limit", 100, "maximum number of tasks, 0 for all")
+	pageSize := flags.Int("page-size", 0, "tasks per request, 0 for the server default")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long the whole query may take")
+	output := flags.String("o", formatTable, "output: table or json")
+	flags.Parse(args)
+
+	out, err := newOutput(os.Stdout, *output, false)
+	if err != nil {
+		return err
+	}
+	client, profile, closeConn, err := connect(conn)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	request, err := filter.request(profile)
+	if err != nil {
+		return err
+	}
+	request.PageSize = int32(*pageSize)
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	remaining := *limit
+	for {
+		if *limit > 0 && (request.PageSize == 0 || int(request.PageSize) > remaining) {
+			request.PageSize = int32(remaining)
+		}
+
+		response, err := client.GetLogs(ctx, request)
+		if err != nil {
+			return err
+		}
+		for _, task := range response.Tasks {
+			if err := out.Write(entryFromProto(task)); err != nil {
+				return err
+			}
+		}
+
+		remaining -= len(response.Tasks)
+		if response.NextPageToken == "" || (*limit > 0 && remaining <= 0) {
+			break
+		}
+		request.PageToken = response.NextPageToken
+	}
+	return out.Flush()
+}
+
+func tail(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ExitOnError)
+	conn := addConnectionFlags(flags)
+	filter := addFilterFlags(flags)
+	replay := flags.Int("replay", 0, "number of recent tasks to print before following")
+	output := flags.String("o", formatTable, "output: table or json")
+	flags.Parse(args)
+
+	out, err := newOutput(os.Stdout, *output, true)
+	if err != nil {
+		return err
+	}
+	client, profile, closeConn, err := connect(conn)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	request, err := filter.request(profile)
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.TailLogs(ctx, &pb.TailRequest{Filter: request, Replay: int32(*replay)})
+	if err != nil {
+		return err
+	}
+	for {
+		response, err := stream.Recv()
+		if ctx.Err() != nil {
+			// Interrupted by the user.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if response.Dropped > 0 {
+			fmt.Fprintf(os.Stderr, "%d tasks were dropped because the output fell behind\n", response.Dropped)
+		}
+		if response.Task != nil {
+			if err := out.Write(entryFromProto(response.Task)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// exportLogs writes the file produced by the ExportLogs RPC and checks it
+// against the trailer. An export to -out that fails is removed.
+func exportLogs(ctx context.Context, args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	conn := addConnectionFlags(flags)
+	filter := addFilterFlags(flags)
+	format := flags.String("format", export.NDJSON, "file format: ndjson or csv")
+	compress := flags.Bool("gzip", false, "compress the file with gzip")
+	path := flags.String("out", "", "file to write, default standard output")
+	flags.Parse(args)
+
+	formats := map[string]pb.ExportFormat{export.NDJSON: pb.ExportFormat_EXPORT_NDJSON, export.CSV: pb.ExportFormat_EXPORT_CSV}
+	exportFormat, ok := formats[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, want %s or %s", *format, export.NDJSON, export.CSV)
+	}
+
+	client, profile, closeConn, err := connect(conn)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	request, err := filter.request(profile)
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.ExportLogs(ctx, &pb.ExportRequest{Filter: request, Format: exportFormat, Gzip: *compress})
+	if err != nil {
+		return err
+	}
+
+	var file io.Writer = os.Stdout
+	if *path != "" {
+		created, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := created.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(*path)
+			}
+		}()
+		file = created
+	}
+
+	hash := sha256.New()
+	out := io.MultiWriter(file, hash)
+	var written int64
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("export ended without a trailer after %d bytes", written)
+		}
+		if err != nil {
+			return err
+		}
+
+		if trailer := chunk.GetTrailer(); trailer != nil {
+			sum := hex.EncodeToString(hash.Sum(nil))
+			if trailer.Bytes != written || trailer.Sha256 != sum {
+				return fmt.Errorf("export is corrupt: got %d bytes with sha256 %s, server sent %d bytes with sha256 %s",
+					written, sum, trailer.Bytes, trailer.Sha256)
+			}
+			if *path != "" {
+				fmt.Fprintf(os.Stderr, "Exported %d tasks to %s (sha256 %s)\n", trailer.Rows, *path, sum)
+			}
+			return nil
+		}
+
+		n, err := out.Write(chunk.GetData())
+		written += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func health(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("health", flag.ExitOnError)
+	conn := addConnectionFlags(flags)
+	timeout := flags.Duration("timeout", 5*time.Second, "how long to wait for the server")
+	flags.Parse(args)
+
+	client, _, closeConn, err := connect(conn)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	response, err := client.HealthCheck(ctx, &pb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	fmt.Println(response.Status)
+	return nil
+}
+
+func connect(conn *connection) (pb.AlertLoggerClient, Profile, func(), error) {
+	clientConn, profile, err := conn.dial()
+	if err != nil {
+		return nil, Profile{}, nil, err
+	}
+	return pb.NewAlertLoggerClient(clientConn), profile, func() { clientConn.Close() }, nil
+}
+
+// filterFlags are the GetLogs filters other than the tenant, which comes
+// from the profile.
+type filterFlags struct {
+	from         *string
+	to           *string
+	tasks        *string
+	types        *string
+	nameContains *string
+	namePrefix   *string
+	disabled     *string
+}
+
+func addFilterFlags(flags *flag.FlagSet) *filterFlags {
+	return &filterFlags{
+		from:         flags.String("from", "", "earliest timestamp, RFC 3339 or a duration before now such as 24h"),
+		to:           flags.String("to", "", "latest timestamp, RFC 3339 or a duration before now"),
+		tasks:        flags.String("task", "", "comma-separated task ids"),
+		types:        flags.String("type", "", "comma-separated task types: INTERVAL, CRON"),
+		nameContains: flags.String("name-contains", "", "only tasks whose name contains this text"),
+		namePrefix:   flags.String("name-prefix", "", "only tasks whose name starts with this text"),
+		disabled:     flags.String("disabled", "", "only disabled (true) or enabled (false) tasks"),
+	}
+}
+
+func (f *filterFlags) request(profile Profile) (*pb.TaskRequest, error) {
+	request := &pb.TaskRequest{
+		Organization: profile.Organization,
+		ProjectId:    profile.ProjectID,
+		NameContains: *f.nameContains,
+		NamePrefix:   *f.namePrefix,
+	}
+
+	var err error
+	if request.StartTime, err = parseTime(*f.from); err != nil {
+		return nil, fmt.Errorf("invalid -from: %v", err)
+	}
+	if request.EndTime, err = parseTime(*f.to); err != nil {
+		return nil, fmt.Errorf("invalid -to: %v", err)
+	}
+
+	for _, field := range splitList(*f.tasks) {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task id %q", field)
+		}
+		request.TaskIds = append(request.TaskIds, id)
+	}
+
+	for _, field := range splitList(*f.types) {
+		value, ok := pb.TaskType_value[strings.ToUpper(field)]
+		if !ok {
+			return nil, fmt.Errorf("unknown task type %q", field)
+		}
+		request.Types = append(request.Types, pb.TaskType(value))
+	}
+
+	if *f.disabled != "" {
+		disabled, err := strconv.ParseBool(*f.disabled)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -disabled: %v", err)
+		}
+		request.Disabled = proto.Bool(disabled)
+	}
+	return request, nil
+}
+
+// parseTime reads an RFC 3339 time, or a duration that is subtracted from
+// now.
+func parseTime(value string) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return timestamppb.New(time.Now().Add(-ago)), nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither an RFC 3339 time nor a duration", value)
+	}
+	return timestamppb.New(parsed), nil
+}
+
+func splitList(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bondzai/logger/internal/export"
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+)
+
+// Output formats of query and tail.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// newOutput returns a writer for the -o flag. JSON is written one task per
+// line, in the same shape as an NDJSON export. A table flushed after every
+// row, as tail needs, is aligned only approximately.
+func newOutput(w io.Writer, format string, flushEach bool) (export.Writer, error) {
+	switch format {
+	case formatTable:
+		return &tableWriter{writer: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0), flushEach: flushEach}, nil
+	case formatJSON:
+		return export.NewWriter(w, export.NDJSON)
+	default:
+		return nil, fmt.Errorf("unknown output %q, want %s or %s", format, formatTable, formatJSON)
+	}
+}
+
+type tableWriter struct {
+	writer    *tabwriter.Writer
+	flushEach bool
+	header    bool
+}
+
+func (t *tableWriter) Write(entry store.Entry) error {
+	if !t.header {
+		fmt.Fprintln(t.writer, "TIME\tTASK\tTYPE\tNAME\tSCHEDULE\tDISABLED")
+		t.header = true
+	}
+
+	task := entry.Task
+	schedule := strings.Join(task.CronExpr, "; ")
+	if task.Type == pb.TaskType_INTERVAL {
+		schedule = fmt.Sprintf("every %s", time.Duration(task.Interval)*time.Second)
+	}
+	fmt.Fprintf(t.writer, "%s\t%d\t%s\t%s\t%s\t%t\n",
+		task.TimeStamp.Format(time.RFC3339), task.ID, task.Type, task.Name, schedule, task.Disabled)
+
+	if t.flushEach {
+		return t.writer.Flush()
+	}
+	return nil
+}
+
+func (t *tableWriter) Flush() error {
+	return t.writer.Flush()
+}
+
+func entryFromProto(task *pb.Task) store.Entry {
+	entry := store.Entry{ID: task.EntryId, Task: model.Task{
+		ID:           int(task.Id),
+		Organization: task.Organization,
+		ProjectID:    int(task.ProjectId),
+		Type:         task.Type,
+		Name:         task.Name,
+		Interval:     task.Interval,
+		CronExpr:     task.CronExpr,
+		Disabled:     task.Disabled,
+	}}
+	if task.Timestamp != nil {
+		entry.Task.TimeStamp = task.Timestamp.AsTime()
+	}
+	return entry
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configEnv     = "LOGCTL_CONFIG"
+	profileEnv    = "LOGCTL_PROFILE"
+	apiKeyEnv     = "LOGCTL_API_KEY"
+	defaultServer = "localhost:50051"
+)
+
+// Profile holds the connection settings of one server. Every field can be
+// overridden by the flag named after its yaml key, with underscores turned
+// into dashes.
+type Profile struct {
+	Address      string `yaml:"address" usage:"server address"`
+	APIKey       string `yaml:"api_key" usage:"API key (env LOGCTL_API_KEY)"`
+	TLS          bool   `yaml:"tls" usage:"connect with TLS; implied by -ca-file and -cert-file"`
+	CAFile       string `yaml:"ca_file" usage:"CA bundle the server certificate is verified against, PEM"`
+	CertFile     string `yaml:"cert_file" usage:"client certificate for mTLS, PEM"`
+	KeyFile      string `yaml:"key_file" usage:"client private key for mTLS, PEM"`
+	ServerName   string `yaml:"server_name" usage:"name expected in the server certificate"`
+	Organization string `yaml:"organization" usage:"organization to query"`
+	ProjectID    int64  `yaml:"project_id" usage:"project to query"`
+}
+
+// profileFile is the YAML file that profiles are read from:
+//
+//	current: prod
+//	profiles:
+//	  prod:
+//	    address: logger.example.com:50051
+//	    tls: true
+//	    api_key: lk_...
+//	    organization: acme
+//	    project_id: 7
+type profileFile struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// connection collects the profile flags of a command.
+type connection struct {
+	flags     *flag.FlagSet
+	file      *string
+	name      *string
+	overrides Profile
+}
+
+func addConnectionFlags(flags *flag.FlagSet) *connection {
+	c := &connection{
+		flags: flags,
+		file:  flags.String("config", os.Getenv(configEnv), "profile file (env "+configEnv+", default "+defaultConfigFile()+")"),
+		name:  flags.String("profile", os.Getenv(profileEnv), "profile to use (env "+profileEnv+", default the file's current profile)"),
+	}
+
+	value := reflect.ValueOf(&c.overrides).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, usage := flagName(field), field.Tag.Get("usage")
+		switch pointer := value.Field(i).Addr().Interface().(type) {
+		case *string:
+			flags.StringVar(pointer, name, "", usage)
+		case *bool:
+			flags.BoolVar(pointer, name, false, usage)
+		case *int64:
+			flags.Int64Var(pointer, name, 0, usage)
+		}
+	}
+	return c
+}
+
+// profile returns the selected profile with the flags that were set applied
+// on top. Call it after the flags are parsed.
+func (c *connection) profile() (Profile, error) {
+	profile, err := c.load()
+	if err != nil {
+		return Profile{}, err
+	}
+	if key := os.Getenv(apiKeyEnv); key != "" {
+		profile.APIKey = key
+	}
+
+	set := make(map[string]bool)
+	c.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	value, overrides := reflect.ValueOf(&profile).Elem(), reflect.ValueOf(c.overrides)
+	for i := 0; i < value.NumField(); i++ {
+		if set[flagName(value.Type().Field(i))] {
+			value.Field(i).Set(overrides.Field(i))
+		}
+	}
+
+	if profile.Address == "" {
+		profile.Address = defaultServer
+	}
+	return profile, nil
+}
+
+func (c *connection) load() (Profile, error) {
+	path := *c.file
+	if path == "" {
+		path = defaultConfigFile()
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && *c.file == "" {
+		if *c.name != "" {
+			return Profile{}, fmt.Errorf("profile %q not found: %s does not exist", *c.name, path)
+		}
+		return Profile{}, nil
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var file profileFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Profile{}, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	name := *c.name
+	if name == "" {
+		name = file.Current
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	profile, ok := file.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	return profile, nil
+}
+
+// dial connects to the server of the profile. The API key is sent with every
+// RPC.
+func (c *connection) dial() (*grpc.ClientConn, Profile, error) {
+	profile, err := c.profile()
+	if err != nil {
+		return nil, Profile{}, err
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if profile.TLS || profile.CAFile != "" || profile.CertFile != "" {
+		config, err := tlsConfig(profile)
+		if err != nil {
+			return nil, Profile{}, err
+		}
+		options[0] = grpc.WithTransportCredentials(credentials.NewTLS(config))
+	}
+	if profile.APIKey != "" {
+		options = append(options, grpc.WithPerRPCCredentials(apiKey(profile.APIKey)))
+	}
+
+	conn, err := grpc.Dial(profile.Address, options...)
+	if err != nil {
+		return nil, Profile{}, fmt.Errorf("failed to connect to %s: %v", profile.Address, err)
+	}
+	return conn, profile, nil
+}
+
+func tlsConfig(profile Profile) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: profile.ServerName}
+
+	if profile.CAFile != "" {
+		bundle, err := os.ReadFile(profile.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", profile.CAFile)
+		}
+	}
+
+	if profile.CertFile != "" || profile.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(profile.CertFile, profile.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// apiKey sends the key the way the server's authenticator expects it.
+type apiKey string
+
+func (k apiKey) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(k)}, nil
+}
+
+// RequireTransportSecurity is false so that keys also work against local
+// plaintext servers.
+func (k apiKey) RequireTransportSecurity() bool {
+	return false
+}
+
+func flagName(field reflect.StructField) string {
+	return strings.ReplaceAll(field.Tag.Get("yaml"), "_", "-")
+}
+
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "logctl.yaml"
+	}
+	return filepath.Join(dir, "logctl", "config.yaml")
+}
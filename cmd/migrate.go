@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bondzai/logger/internal/config"
+	"github.com/bondzai/logger/internal/mongodb"
+	"github.com/bondzai/logger/internal/query"
+)
+
+// indexSpecs declares the indexes of every collection the service uses.
+func indexSpecs(cfg *config.Config) []mongodb.IndexSpec {
+	return []mongodb.IndexSpec{
+		{
+			Collection: cfg.Mongo.Collection,
+			Indexes:    query.LogIndexes,
+			Managed:    []string{mongodb.TTLIndexName("timestamp")},
+		},
+		{Collection: cfg.Mongo.Executions, Indexes: query.ExecutionIndexes},
+		{Collection: cfg.Mongo.MissedRuns, Indexes: query.MissedRunIndexes},
+		{Collection: cfg.Mongo.Retention, Indexes: query.RetentionPolicyIndexes},
+		{Collection: cfg.Mongo.APIKeys, Indexes: query.APIKeyIndexes},
+	}
+}
+
+// migrate reconciles the indexes and converts data written by older versions.
+// Every step runs even when an earlier one fails.
+func migrate(ctx context.Context, mongo *mongodb.MongoDB, cfg *config.Config) error {
+	var errs []error
+
+	for _, spec := range indexSpecs(cfg) {
+		report, err := mongo.ReconcileIndexes(ctx, spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Reconciled %s", report)
+	}
+
+	converted, err := mongo.ConvertStringTimestamps(ctx, cfg.Mongo.Collection)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to convert string timestamps: %v", err))
+	} else if converted > 0 {
+		log.Printf("Converted %d string timestamps to dates", converted)
+	}
+
+	return errors.Join(errs...)
+}
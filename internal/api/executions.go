@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *LoggerServer) GetExecutions(ctx context.Context, req *pb.ExecutionRequest) (*pb.ExecutionResponse, error) {
+	if err := validateExecutionRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+
+	pageSize := int64(req.PageSize)
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = defaultLimit
+	}
+
+	// One extra entry tells us whether another page follows.
+	q := store.ExecutionQuery{Filter: query.FromExecutionRequest(req), Limit: pageSize + 1}
+
+	if req.PageToken != "" {
+		cursor, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+		}
+		q.After = cursor
+	}
+
+	entries, err := s.Executions.FindExecutions(ctx, q)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get executions: %v", err)
+	}
+
+	response := &pb.ExecutionResponse{}
+	if int64(len(entries)) > pageSize {
+		entries = entries[:pageSize]
+		response.NextPageToken = encodePageToken(entries[len(entries)-1].Cursor())
+	}
+
+	response.Executions = make([]*pb.Execution, len(entries))
+	for i, entry := range entries {
+		response.Executions[i] = toProtoExecution(entry)
+	}
+	return response, nil
+}
+
+func validateExecutionRequest(req *pb.ExecutionRequest) error {
+	if req.Organization == "" {
+		return fmt.Errorf("organization cannot be empty")
+	}
+	if req.ProjectId == 0 {
+		return fmt.Errorf("project id cannot be empty")
+	}
+	return query.FromExecutionRequest(req).Validate()
+}
+
+func toProtoExecution(entry store.ExecutionEntry) *pb.Execution {
+	execution := entry.Execution
+	protoExecution := &pb.Execution{
+		TaskId:       int64(execution.TaskID),
+		Organization: execution.Organization,
+		ProjectId:    int64(execution.ProjectID),
+		Outcome:      execution.Outcome,
+		StartedAt:    timestamppb.New(execution.StartedAt),
+		DurationMs:   execution.DurationMs,
+		Error:        execution.Error,
+		Attempt:      int32(execution.Attempt),
+		Schedule:     execution.Schedule,
+		EntryId:      entry.ID,
+	}
+	if !execution.EndedAt.IsZero() {
+		protoExecution.EndedAt = timestamppb.New(execution.EndedAt)
+	}
+	return protoExecution
+}
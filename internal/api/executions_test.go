@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store/memory"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetExecutions tests that executions are filtered and paged newest first.
+func TestGetExecutions(t *testing.T) {
+	executions := memory.NewExecutionStore()
+	var documents []map[string]interface{}
+	for i := 1; i <= 5; i++ {
+		outcome := pb.ExecutionOutcome_OUTCOME_SUCCESS
+		if i%2 == 0 {
+			outcome = pb.ExecutionOutcome_OUTCOME_FAILURE
+		}
+		document := map[string]interface{}{
+			"task_id":      float64(i),
+			"organization": "acme",
+			"project_id":   float64(7),
+			"outcome":      float64(outcome),
+			"started_at":   fmt.Sprintf("2024-01-03T0%d:00:00Z", i),
+		}
+		model.NormalizeExecution(document)
+		documents = append(documents, document)
+	}
+	require.NoError(t, executions.InsertMany(context.Background(), documents))
+
+	server := &LoggerServer{Executions: executions}
+	request := &pb.ExecutionRequest{
+		Organization: "acme",
+		ProjectId:    7,
+		PageSize:     2,
+		Outcomes:     []pb.ExecutionOutcome{pb.ExecutionOutcome_OUTCOME_SUCCESS},
+	}
+
+	response, err := server.GetExecutions(context.Background(), request)
+	require.NoError(t, err, "GetExecutions should not return an error")
+	assert.Equal(t, []int64{5, 3}, executionTaskIDs(response), "First page should hold the newest matches")
+	assert.NotEmpty(t, response.NextPageToken, "A next page token should be returned")
+
+	request.PageToken = response.NextPageToken
+	response, err = server.GetExecutions(context.Background(), request)
+	require.NoError(t, err, "GetExecutions should not return an error")
+	assert.Equal(t, []int64{1}, executionTaskIDs(response), "Second page should hold the rest")
+	assert.Empty(t, response.NextPageToken, "The last page should have no next page token")
+}
+
+func executionTaskIDs(response *pb.ExecutionResponse) []int64 {
+	ids := make([]int64, len(response.Executions))
+	for i, execution := range response.Executions {
+		ids[i] = execution.TaskId
+	}
+	return ids
+}
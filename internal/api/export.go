@@ -0,0 +1,136 @@
+package api
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+
+	"github.com/bondzai/logger/internal/export"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	exportChunkSize = 64 << 10
+
+	exportRowsTrailer   = "x-export-rows"
+	exportSHA256Trailer = "x-export-sha256"
+)
+
+var exportFormats = map[pb.ExportFormat]string{
+	pb.ExportFormat_EXPORT_NDJSON: export.NDJSON,
+	pb.ExportFormat_EXPORT_CSV:    export.CSV,
+}
+
+// ExportLogs streams the logs matching the filter as a file, encoded straight
+// from the store cursor. An export is complete only when the stream ends with
+// the trailer; clients should check the row count and checksum against what
+// they wrote.
+func (s *LoggerServer) ExportLogs(req *pb.ExportRequest, stream pb.AlertLogger_ExportLogsServer) error {
+	if req.Filter == nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", fmt.Errorf("filter cannot be empty"))
+	}
+	if err := s.validateGetLogsRequest(req.Filter); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+	format, ok := exportFormats[req.Format]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", fmt.Errorf("unknown format %v", req.Format))
+	}
+
+	q := store.Query{Filter: query.FromTaskRequest(req.Filter)}
+	if req.Filter.Limit > 0 {
+		q.Limit = int64(req.Filter.Limit)
+	}
+
+	chunks := &chunkWriter{stream: stream, hash: sha256.New(), buffer: make([]byte, 0, exportChunkSize)}
+	var out io.Writer = chunks
+	var compressor *gzip.Writer
+	if req.Gzip {
+		compressor = gzip.NewWriter(chunks)
+		out = compressor
+	}
+
+	writer, err := export.NewWriter(out, format)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+
+	ctx := stream.Context()
+	var rows int64
+	err = s.Store.Each(ctx, q, func(entry store.Entry) error {
+		rows++
+		return writer.Write(entry)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil && compressor != nil {
+		err = compressor.Close()
+	}
+	if err == nil {
+		err = chunks.flush()
+	}
+	if err != nil {
+		return streamError(ctx, err)
+	}
+
+	trailer := &pb.ExportTrailer{Rows: rows, Bytes: chunks.written, Sha256: hex.EncodeToString(chunks.hash.Sum(nil))}
+	stream.SetTrailer(metadata.Pairs(
+		exportRowsTrailer, strconv.FormatInt(trailer.Rows, 10),
+		exportSHA256Trailer, trailer.Sha256,
+	))
+	if err := stream.Send(&pb.ExportChunk{Content: &pb.ExportChunk_Trailer{Trailer: trailer}}); err != nil {
+		return streamError(ctx, err)
+	}
+	return nil
+}
+
+// chunkWriter sends what is written to it in messages of exportChunkSize
+// bytes, hashing them on the way.
+type chunkWriter struct {
+	stream  pb.AlertLogger_ExportLogsServer
+	hash    hash.Hash
+	buffer  []byte
+	written int64
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := cap(w.buffer) - len(w.buffer)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buffer = append(w.buffer, p[:free]...)
+		p = p[free:]
+
+		if len(w.buffer) == cap(w.buffer) {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *chunkWriter) flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+
+	w.hash.Write(w.buffer)
+	w.written += int64(len(w.buffer))
+	err := w.stream.Send(&pb.ExportChunk{Content: &pb.ExportChunk_Data{Data: w.buffer}})
+	// A sent message must not be modified, so the next chunk gets a new buffer.
+	w.buffer = make([]byte, 0, exportChunkSize)
+	return err
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store/memory"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type exportStream struct {
+	grpc.ServerStream
+	chunks   int
+	data     bytes.Buffer
+	trailer  *pb.ExportTrailer
+	metadata metadata.MD
+}
+
+func (s *exportStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *exportStream) Send(chunk *pb.ExportChunk) error {
+	if trailer := chunk.GetTrailer(); trailer != nil {
+		s.trailer = trailer
+		return nil
+	}
+	s.chunks++
+	s.data.Write(chunk.GetData())
+	return nil
+}
+
+func (s *exportStream) SetTrailer(md metadata.MD) {
+	s.metadata = md
+}
+
+// TestExportLogs tests that exports are chunked, optionally compressed and
+// end with a trailer that matches the bytes sent.
+func TestExportLogs(t *testing.T) {
+	logs := memory.NewLogStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1500; i++ {
+		document := map[string]interface{}{
+			"task_id": float64(i), "organization": "acme", "project_id": float64(7), "type": float64(2),
+			"task_name": "nightly backup of the billing database", "task_cron_expression": []interface{}{"0 2 * * *"},
+		}
+		model.NormalizeTask(document, start.Add(time.Duration(i)*time.Second))
+		require.NoError(t, logs.Insert(context.Background(), document))
+	}
+	server := &LoggerServer{Store: logs}
+	filter := &pb.TaskRequest{Organization: "acme", ProjectId: 7}
+
+	stream := &exportStream{}
+	require.NoError(t, server.ExportLogs(&pb.ExportRequest{Filter: filter}, stream))
+	assert.Greater(t, stream.chunks, 1, "A large export should be split into chunks")
+	assert.Equal(t, 1500, strings.Count(stream.data.String(), "\n"), "NDJSON should have a line per row")
+	sum := sha256.Sum256(stream.data.Bytes())
+	require.NotNil(t, stream.trailer, "The export should end with a trailer")
+	assert.Equal(t, &pb.ExportTrailer{Rows: 1500, Bytes: int64(stream.data.Len()), Sha256: hex.EncodeToString(sum[:])}, stream.trailer)
+	assert.Equal(t, []string{"1500"}, stream.metadata.Get(exportRowsTrailer), "Rows should be in the trailing metadata")
+	assert.Equal(t, []string{stream.trailer.Sha256}, stream.metadata.Get(exportSHA256Trailer), "The checksum should be in the trailing metadata")
+
+	filter.Limit = 2
+	stream = &exportStream{}
+	require.NoError(t, server.ExportLogs(&pb.ExportRequest{Filter: filter, Format: pb.ExportFormat_EXPORT_CSV, Gzip: true}, stream))
+	sum = sha256.Sum256(stream.data.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), stream.trailer.Sha256, "The checksum should cover the compressed bytes")
+
+	reader, err := gzip.NewReader(&stream.data)
+	require.NoError(t, err)
+	file, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t,
+		"entry_id,task_id,organization,project_id,type,task_name,interval,task_cron_expression,disabled,timestamp",
+		strings.SplitN(string(file), "\n", 2)[0], "CSV should start with the header")
+	assert.Equal(t, 3, strings.Count(string(file), "\n"), "Limit should bound the rows")
+	assert.Equal(t, int64(2), stream.trailer.Rows)
+
+	err = server.ExportLogs(&pb.ExportRequest{}, &exportStream{})
+	assert.Error(t, err, "A filter should be required")
+}
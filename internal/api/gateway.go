@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bondzai/logger/internal/certs"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	openAPIPath    = "/openapi.json"
+	apiKeyHeader   = "X-Api-Key"
+	pipeBufferSize = 1 << 20
+)
+
+// Gateway serves the AlertLogger service as REST/JSON, with routes generated
+// from proto/logger_http.yaml. Requests are proxied over an in-memory
+// connection to a private gRPC server that runs the same interceptors as the
+// public one, so filters, pagination, auth and metrics behave identically.
+// The client certificate verified by the gateway is forwarded with each RPC,
+// so that it is audited as if the client had called the gRPC server.
+type Gateway struct {
+	server   *http.Server
+	rpc      *grpc.Server
+	listener *bufconn.Listener
+	conn     *grpc.ClientConn
+	tls      bool
+}
+
+func NewGateway(address string, service *LoggerServer, options Options) (*Gateway, error) {
+	// Only the gateway can reach this server, so it may trust the certificates
+	// the gateway forwards.
+	rpc := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(certs.TrustForwardedUnary()),
+		grpc.ChainStreamInterceptor(certs.TrustForwardedStream()),
+	}, interceptors(options)...)...)
+	pb.RegisterAlertLoggerServer(rpc, service)
+
+	listener := bufconn.Listen(pipeBufferSize)
+	conn, err := grpc.Dial("passthrough:///gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect the gateway: %v", err)
+	}
+
+	gateway := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithMetadata(forwardCertificate),
+	)
+	if err := pb.RegisterAlertLoggerHandler(context.Background(), gateway, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to register gateway handlers: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", gateway)
+	mux.HandleFunc(openAPIPath, serveOpenAPI)
+
+	return &Gateway{
+		server: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			TLSConfig:         options.TLS,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		rpc:      rpc,
+		listener: listener,
+		conn:     conn,
+		tls:      options.TLS != nil,
+	}, nil
+}
+
+func (g *Gateway) Serve() error {
+	go func() {
+		if err := g.rpc.Serve(g.listener); err != nil {
+			log.Printf("Failed to serve gateway RPCs: %v", err)
+		}
+	}()
+
+	log.Println("REST gateway listening on", g.server.Addr)
+	var err error
+	if g.tls {
+		// The certificates come from the TLS config.
+		err = g.server.ListenAndServeTLS("", "")
+	} else {
+		err = g.server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests and waits for running ones to finish.
+// When ctx expires first, the remaining RPCs are cancelled.
+func (g *Gateway) Shutdown(ctx context.Context) error {
+	err := g.server.Shutdown(ctx)
+	g.rpc.Stop()
+	g.conn.Close()
+	return err
+}
+
+// incomingHeader forwards the API key header as gRPC metadata in addition to
+// the headers the gateway forwards by default, which include Authorization.
+// A forwarded certificate can only come from forwardCertificate.
+func incomingHeader(key string) (string, bool) {
+	if strings.EqualFold(key, apiKeyHeader) {
+		return strings.ToLower(key), true
+	}
+	name, ok := runtime.DefaultHeaderMatcher(key)
+	if ok && certs.IsForwardedMetadata(strings.ToLower(name)) {
+		return "", false
+	}
+	return name, ok
+}
+
+func forwardCertificate(ctx context.Context, r *http.Request) metadata.MD {
+	return certs.ForwardedMetadata(r.TLS)
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(pb.OpenAPI)
+}
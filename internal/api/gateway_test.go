@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/auth"
+	"github.com/bondzai/logger/internal/certs"
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type keyStore map[string]auth.Key
+
+func (s keyStore) Key(ctx context.Context, id string) (auth.Key, error) {
+	key, ok := s[id]
+	if !ok {
+		return auth.Key{}, auth.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// TestGateway tests that REST requests reach the RPCs with their filters,
+// pagination and API key.
+func TestGateway(t *testing.T) {
+	logs := memory.NewLogStore()
+	for _, name := range []string{"backup", "report", "cleanup"} {
+		document := map[string]interface{}{
+			"task_id": float64(1), "organization": "acme", "project_id": float64(7), "type": float64(1), "task_name": name,
+		}
+		model.NormalizeTask(document, time.Now())
+		require.NoError(t, logs.Insert(context.Background(), document))
+	}
+
+	key, token, err := auth.NewKey("dashboards", "acme", []int64{7}, 0)
+	require.NoError(t, err)
+
+	gateway, err := NewGateway("localhost:0", &LoggerServer{Store: logs}, Options{
+		Authenticator: auth.NewAuthenticator(keyStore{key.ID: key}, time.Minute),
+	})
+	require.NoError(t, err)
+	go gateway.rpc.Serve(gateway.listener)
+	defer gateway.Shutdown(context.Background())
+
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			request.Header[name] = values
+		}
+		recorder := httptest.NewRecorder()
+		gateway.server.Handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	response := get("/v1/organizations/acme/projects/7/logs?page_size=2&types=INTERVAL", http.Header{"X-Api-Key": {token}})
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	var page struct {
+		Tasks         []map[string]interface{} `json:"tasks"`
+		NextPageToken string                   `json:"nextPageToken"`
+	}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.Len(t, page.Tasks, 2, "The page size should apply")
+	assert.NotEmpty(t, page.NextPageToken, "A next page token should be returned")
+
+	response = get("/v1/organizations/acme/projects/7/logs?page_token="+page.NextPageToken, http.Header{"Authorization": {"Bearer " + token}})
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &page))
+	assert.Len(t, page.Tasks, 1, "The second page should hold the rest")
+
+	response = get("/v1/organizations/acme/projects/7/logs", nil)
+	assert.Equal(t, http.StatusUnauthorized, response.Code, "A key should be required")
+
+	response = get("/v1/organizations/acme/projects/8/logs", http.Header{"X-Api-Key": {token}})
+	assert.Equal(t, http.StatusForbidden, response.Code, "A key should not access other projects")
+
+	response = get("/openapi.json", nil)
+	assert.Equal(t, http.StatusOK, response.Code, "The OpenAPI document should be public")
+	assert.Contains(t, response.Body.String(), "/v1/organizations/{organization}/projects/{projectId}/logs")
+}
+
+// identityStore records the client certificate identity each query ran with.
+type identityStore struct {
+	store.LogStore
+	mu         sync.Mutex
+	identities []string
+}
+
+func (s *identityStore) Find(ctx context.Context, q store.Query) ([]store.Entry, error) {
+	identity, _ := certs.IdentityFromContext(ctx)
+	s.mu.Lock()
+	s.identities = append(s.identities, identity.CommonName)
+	s.mu.Unlock()
+	return s.LogStore.Find(ctx, q)
+}
+
+// issueCert returns a certificate signed by parent, or self-signed when
+// parent is nil.
+func issueCert(t *testing.T, serial int64, commonName string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// TestGatewayClientCertificate tests that the client certificate verified by
+// the gateway reaches the RPC, and that clients cannot forge one.
+func TestGatewayClientCertificate(t *testing.T) {
+	ca := issueCert(t, 1, "test ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	serverTLS := &tls.Config{
+		Certificates: []tls.Certificate{issueCert(t, 2, "logger", &ca)},
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	logs := &identityStore{LogStore: memory.NewLogStore()}
+	gateway, err := NewGateway("localhost:0", &LoggerServer{Store: logs}, Options{TLS: serverTLS})
+	require.NoError(t, err)
+	go gateway.rpc.Serve(gateway.listener)
+	defer gateway.Shutdown(context.Background())
+
+	server := httptest.NewUnstartedServer(gateway.server.Handler)
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{issueCert(t, 3, "dashboard", &ca)},
+	}}}
+	response, err := client.Get(server.URL + "/v1/organizations/acme/projects/7/logs")
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	forged := issueCert(t, 4, "admin", nil)
+	request := httptest.NewRequest(http.MethodGet, "/v1/organizations/acme/projects/7/logs", nil)
+	request.Header.Set("Grpc-Metadata-X-Logger-Client-Cert-Bin", base64.StdEncoding.EncodeToString(forged.Leaf.Raw))
+	recorder := httptest.NewRecorder()
+	gateway.server.Handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	assert.Equal(t, []string{"dashboard", ""}, logs.identities, "Only the certificate verified by the gateway should be forwarded")
+}
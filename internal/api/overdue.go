@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *LoggerServer) ListOverdueTasks(ctx context.Context, req *pb.OverdueRequest) (*pb.OverdueResponse, error) {
+	if s.Detector == nil {
+		return nil, status.Errorf(codes.Unavailable, "Missed-run detection is disabled")
+	}
+	if req.Organization == "" || req.ProjectId == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", fmt.Errorf("organization and project id cannot be empty"))
+	}
+
+	overdue := s.Detector.Overdue(req.Organization, req.ProjectId)
+
+	response := &pb.OverdueResponse{Tasks: make([]*pb.OverdueTask, len(overdue))}
+	for i, task := range overdue {
+		response.Tasks[i] = &pb.OverdueTask{
+			Task:       toProtoTask(store.Entry{Task: task.Task}),
+			LastSeen:   timestamppb.New(task.LastSeen),
+			ExpectedAt: timestamppb.New(task.ExpectedAt),
+		}
+	}
+	return response, nil
+}
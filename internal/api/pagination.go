@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bondzai/logger/internal/store"
+)
+
+// Page tokens are the opaque encoding of the sort key of the last task on a
+// page; the next page starts strictly after it.
+func encodePageToken(cursor store.Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (*store.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed page token")
+	}
+
+	cursor := &store.Cursor{}
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("malformed page token")
+	}
+
+	return cursor, nil
+}
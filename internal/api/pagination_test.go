@@ -0,0 +1,25 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPageToken tests that a page token round-trips the sort key of an entry.
+func TestPageToken(t *testing.T) {
+	stamp := time.Date(2024, 1, 4, 10, 0, 0, 123000000, time.UTC)
+	cursor := store.Cursor{TimeStamp: stamp, ID: "659680e0c2a3b0e2e1f7d001"}
+
+	decoded, err := decodePageToken(encodePageToken(cursor))
+	assert.NoError(t, err, "decodePageToken should not return an error")
+	assert.Equal(t, cursor, *decoded, "Token should carry the cursor unchanged")
+
+	_, err = decodePageToken("not a token")
+	assert.Error(t, err, "decodePageToken should reject garbage")
+
+	_, err = decodePageToken(encodePageToken(store.Cursor{TimeStamp: stamp}))
+	assert.Error(t, err, "decodePageToken should reject a token without an id")
+}
@@ -2,46 +2,127 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 
-	"github.com/bondzai/logger/internal/model"
-	"github.com/bondzai/logger/internal/mongodb"
+	"github.com/bondzai/logger/internal/auth"
+	"github.com/bondzai/logger/internal/cache"
+	"github.com/bondzai/logger/internal/health"
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/schedule"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/tail"
 	pb "github.com/bondzai/logger/proto"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
 	protocol     = "tcp"
-	port         = ":50051"
 	defaultLimit = 1000
+	maxPageSize  = 1000
 )
 
 type LoggerServer struct {
 	pb.UnimplementedAlertLoggerServer
-	Database *mongodb.MongoDB
+	Store      store.LogStore
+	Executions store.ExecutionStore
+	Health     *health.Checker
+	Tail       *tail.Hub
+	// Cache is optional; GetLogs goes straight to the store when it is nil.
+	Cache *cache.QueryCache
+	// Detector is optional; ListOverdueTasks is unavailable when it is nil.
+	Detector *schedule.Detector
 }
 
-func StartGRPCServer(database *mongodb.MongoDB) error {
-	listener, err := net.Listen(protocol, port)
+type GRPCServer struct {
+	address string
+	server  *grpc.Server
+}
+
+// Options are the optional parts of a GRPCServer or Gateway.
+type Options struct {
+	// Authenticator requires an API key on every RPC when set.
+	Authenticator *auth.Authenticator
+	// TLS secures the listeners; they serve plaintext when it is nil.
+	TLS *tls.Config
+	// Metrics labels RPCs with the organization of their key, or only with
+	// the listed organizations when there is no Authenticator.
+	Metrics metrics.Labels
+}
+
+func NewGRPCServer(address string, service *LoggerServer, options Options) *GRPCServer {
+	serverOptions := interceptors(options)
+	if options.TLS != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(options.TLS)))
+	}
+
+	server := grpc.NewServer(serverOptions...)
+	pb.RegisterAlertLoggerServer(server, service)
+	service.Health.Register(server)
+
+	return &GRPCServer{address: address, server: server}
+}
+
+// interceptors are shared by every server of the service, so that an RPC is
+// measured and authenticated the same way whichever way it arrives.
+func interceptors(options Options) []grpc.ServerOption {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if options.Authenticator != nil {
+		unary = append(unary, options.Authenticator.UnaryServerInterceptor())
+		stream = append(stream, options.Authenticator.StreamServerInterceptor())
+	}
+	// Metrics come after authentication so that they are labelled with the
+	// organization of the caller's key rather than whatever was requested.
+	unary = append(unary, metrics.UnaryServerInterceptor(options.Metrics))
+	stream = append(stream, metrics.StreamServerInterceptor(options.Metrics))
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+func (s *GRPCServer) Serve() error {
+	listener, err := net.Listen(protocol, s.address)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	server := grpc.NewServer()
-	pb.RegisterAlertLoggerServer(server, &LoggerServer{Database: database})
+	log.Println("gRPC server listening on", s.address)
+	return s.server.Serve(listener)
+}
 
-	log.Println("gRPC server listening on", port)
-	return server.Serve(listener)
+// Shutdown stops accepting RPCs and waits for running ones to finish. When
+// ctx expires first, the remaining RPCs are cancelled.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("forced gRPC server stop: %v", ctx.Err())
+	}
 }
 
 func (s *LoggerServer) HealthCheck(ctx context.Context, request *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
+	if !s.Health.Ready() {
+		return nil, status.Errorf(codes.Unavailable, "Dependencies are not ready")
+	}
+
 	message := fmt.Sprintf("Health check successful.%s", request)
 	return &pb.HealthCheckResponse{Status: message}, nil
 }
@@ -53,7 +134,7 @@ func (s *LoggerServer) validateGetLogsRequest(req *pb.TaskRequest) error {
 	if req.ProjectId == 0 {
 		return fmt.Errorf("project id cannot be empty")
 	}
-	return nil
+	return query.FromTaskRequest(req).Validate()
 }
 
 func (s *LoggerServer) GetLogs(ctx context.Context, req *pb.TaskRequest) (*pb.TaskResponse, error) {
@@ -61,77 +142,82 @@ func (s *LoggerServer) GetLogs(ctx context.Context, req *pb.TaskRequest) (*pb.Ta
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
 	}
 
-	query := buildMongoQuery(req)
+	var cacheKey string
+	if s.Cache != nil {
+		response, key, ok := s.Cache.Get(ctx, req)
+		if ok {
+			return response, nil
+		}
+		cacheKey = key
+	}
+
+	pageSize := resolvePageSize(req)
 
-	findOptions := buildMongoFindOptions(req.Limit)
+	// One extra entry tells us whether another page follows.
+	q := store.Query{Filter: query.FromTaskRequest(req), Limit: pageSize + 1}
+
+	if req.PageToken != "" {
+		cursor, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+		}
+		q.After = cursor
+	}
 
-	results, err := s.Database.FindDocuments("logs", query, findOptions)
+	entries, err := s.Store.Find(ctx, q)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to get logs: %v", err)
 	}
 
-	tasks := convertToProtoTasks(results)
-	return &pb.TaskResponse{Tasks: tasks}, nil
-}
-
-func buildMongoQuery(req *pb.TaskRequest) bson.D {
-	query := bson.D{}
-
-	if req.Organization != "" {
-		query = append(query, bson.E{Key: "organization", Value: req.Organization})
+	response := &pb.TaskResponse{}
+	if int64(len(entries)) > pageSize {
+		entries = entries[:pageSize]
+		response.NextPageToken = encodePageToken(entries[len(entries)-1].Cursor())
 	}
 
-	if req.ProjectId != 0 {
-		query = append(query, bson.E{Key: "project_id", Value: req.ProjectId})
+	response.Tasks = convertToProtoTasks(entries)
+	if s.Cache != nil {
+		s.Cache.Set(ctx, cacheKey, response)
 	}
-
-	return query
+	return response, nil
 }
 
-func buildMongoFindOptions(limit int32) *options.FindOptions {
-	findOptions := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}})
-
-	if limit > 0 {
-		return findOptions.SetLimit(int64(limit))
+func resolvePageSize(req *pb.TaskRequest) int64 {
+	switch {
+	case req.PageSize > maxPageSize:
+		return maxPageSize
+	case req.PageSize > 0:
+		return int64(req.PageSize)
+	case req.Limit > 0:
+		return int64(req.Limit)
+	default:
+		return defaultLimit
 	}
-
-	return findOptions.SetLimit(defaultLimit)
 }
 
-func convertToProtoTasks(results []interface{}) []*pb.Task {
-	var tasks []*pb.Task
-	for _, result := range results {
-		document, ok := result.(primitive.D)
-		if !ok {
-			log.Printf("Unexpected document format: %+v", result)
-			continue
-		}
-
-		data, err := bson.Marshal(document)
-		if err != nil {
-			log.Printf("Failed to marshal document: %v", err)
-			continue
-		}
-
-		var task model.Task
-		err = bson.Unmarshal(data, &task)
-		if err != nil {
-			log.Printf("Failed to unmarshal document: %v", err)
-			continue
-		}
-
-		pbTask := &pb.Task{
-			Id:           int64(task.ID),
-			Organization: task.Organization,
-			ProjectId:    int64(task.ProjectID),
-			Type:         task.Type,
-			Name:         task.Name,
-			Interval:     task.Interval,
-			CronExpr:     task.CronExpr,
-			Disabled:     task.Disabled,
-		}
-
-		tasks = append(tasks, pbTask)
+func convertToProtoTasks(entries []store.Entry) []*pb.Task {
+	tasks := make([]*pb.Task, len(entries))
+	for i, entry := range entries {
+		tasks[i] = toProtoTask(entry)
 	}
 	return tasks
 }
+
+func toProtoTask(entry store.Entry) *pb.Task {
+	task := entry.Task
+	protoTask := &pb.Task{
+		Id:           int64(task.ID),
+		Organization: task.Organization,
+		ProjectId:    int64(task.ProjectID),
+		Type:         task.Type,
+		Name:         task.Name,
+		Interval:     task.Interval,
+		CronExpr:     task.CronExpr,
+		Disabled:     task.Disabled,
+		EntryId:      entry.ID,
+	}
+	if !task.TimeStamp.IsZero() {
+		protoTask.Timestamp = timestamppb.New(task.TimeStamp)
+	}
+	return protoTask
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultStreamChunk = 500
+
+// StreamLogs sends the same results as GetLogs, in page_size chunks, straight
+// from the store cursor, starting after page_token like GetLogs. It is not
+// capped at defaultLimit: limit bounds the total only when set.
+func (s *LoggerServer) StreamLogs(req *pb.TaskRequest, stream pb.AlertLogger_StreamLogsServer) error {
+	if err := s.validateGetLogsRequest(req); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+
+	chunkSize := int(req.PageSize)
+	if chunkSize <= 0 || chunkSize > maxPageSize {
+		chunkSize = defaultStreamChunk
+	}
+
+	q := store.Query{Filter: query.FromTaskRequest(req)}
+	if req.Limit > 0 {
+		q.Limit = int64(req.Limit)
+	}
+	if req.PageToken != "" {
+		cursor, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+		}
+		q.After = cursor
+	}
+
+	ctx := stream.Context()
+	chunk := make([]*pb.Task, 0, chunkSize)
+
+	err := s.Store.Each(ctx, q, func(entry store.Entry) error {
+		chunk = append(chunk, toProtoTask(entry))
+		if len(chunk) < chunkSize {
+			return nil
+		}
+
+		err := stream.Send(&pb.TaskResponse{Tasks: chunk})
+		chunk = make([]*pb.Task, 0, chunkSize)
+		return err
+	})
+	if err != nil {
+		return streamError(ctx, err)
+	}
+
+	if len(chunk) > 0 {
+		if err := stream.Send(&pb.TaskResponse{Tasks: chunk}); err != nil {
+			return streamError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+func streamError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Internal, "Failed to stream logs: %v", err)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/store/memory"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// countingStore counts the entries read from the store and reports how each
+// iteration ended.
+type countingStore struct {
+	store.LogStore
+	read atomic.Int64
+	done chan error
+}
+
+func (s *countingStore) Each(ctx context.Context, q store.Query, fn func(store.Entry) error) error {
+	err := s.LogStore.Each(ctx, q, func(entry store.Entry) error {
+		s.read.Add(1)
+		return fn(entry)
+	})
+	s.done <- err
+	return err
+}
+
+// dialStream serves the store over bufconn and returns a connected client.
+func dialStream(t *testing.T, logs store.LogStore) pb.AlertLoggerClient {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterAlertLoggerServer(server, &LoggerServer{Store: logs})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewAlertLoggerClient(conn)
+}
+
+func streamStore(t *testing.T, count int) *countingStore {
+	logs := memory.NewLogStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	documents := make([]map[string]interface{}, 0, count)
+	// Newest first, so that the memory store appends every entry.
+	for i := count - 1; i >= 0; i-- {
+		document := map[string]interface{}{
+			"task_id": float64(i), "organization": "acme", "project_id": float64(7), "type": float64(1),
+			"task_name": "nightly backup of the billing database",
+		}
+		model.NormalizeTask(document, start.Add(time.Duration(i)*time.Second))
+		documents = append(documents, document)
+	}
+	require.NoError(t, logs.InsertMany(context.Background(), documents))
+	return &countingStore{LogStore: logs, done: make(chan error, 1)}
+}
+
+// TestStreamLogs tests that results arrive in page_size chunks, newest first,
+// and that limit bounds the total.
+func TestStreamLogs(t *testing.T) {
+	logs := streamStore(t, 1200)
+	client := dialStream(t, logs)
+
+	stream, err := client.StreamLogs(context.Background(), &pb.TaskRequest{Organization: "acme", ProjectId: 7, PageSize: 500})
+	require.NoError(t, err)
+	var sizes []int
+	var ids []int64
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			require.Equal(t, io.EOF, err)
+			break
+		}
+		sizes = append(sizes, len(response.Tasks))
+		for _, task := range response.Tasks {
+			ids = append(ids, task.Id)
+		}
+	}
+	assert.Equal(t, []int{500, 500, 200}, sizes, "Results should be sent in page_size chunks")
+	require.Len(t, ids, 1200)
+	assert.Equal(t, int64(1199), ids[0], "Results should be newest first")
+	assert.Equal(t, int64(0), ids[1199])
+	assert.NoError(t, <-logs.done)
+
+	stream, err = client.StreamLogs(context.Background(), &pb.TaskRequest{Organization: "acme", ProjectId: 7, Limit: 700})
+	require.NoError(t, err)
+	total := 0
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		total += len(response.Tasks)
+	}
+	assert.Equal(t, 700, total, "Limit should bound the total")
+	<-logs.done
+
+	page, err := client.GetLogs(context.Background(), &pb.TaskRequest{Organization: "acme", ProjectId: 7, PageSize: 100})
+	require.NoError(t, err)
+	stream, err = client.StreamLogs(context.Background(), &pb.TaskRequest{Organization: "acme", ProjectId: 7, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1099), response.Tasks[0].Id, "The stream should start after page_token")
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	<-logs.done
+
+	stream, err = client.StreamLogs(context.Background(), &pb.TaskRequest{Organization: "acme", ProjectId: 7, PageToken: "not a token"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "A malformed page_token should be rejected")
+
+	stream, err = client.StreamLogs(context.Background(), &pb.TaskRequest{Organization: "acme"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "A project should be required")
+}
+
+// TestStreamLogsBackPressure tests that a client that stops reading holds the
+// store cursor back, and that cancelling the call ends the iteration.
+func TestStreamLogsBackPressure(t *testing.T) {
+	const count = 20000
+	logs := streamStore(t, count)
+	client := dialStream(t, logs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.StreamLogs(ctx, &pb.TaskRequest{Organization: "acme", ProjectId: 7, PageSize: 100})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Len(t, first.Tasks, 100)
+
+	// Wait for the server to stall on flow control.
+	read := int64(-1)
+	for read != logs.read.Load() {
+		read = logs.read.Load()
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Less(t, read, int64(count), "The server should stop reading while the client does not")
+
+	cancel()
+	select {
+	case err := <-logs.done:
+		assert.Equal(t, codes.Canceled, status.Code(err), "Cancelling should stop the store iteration")
+	case <-time.After(time.Second):
+		t.Fatal("The store iteration should end when the call is cancelled")
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Less(t, logs.read.Load(), int64(count), "No more entries should be read after cancelling")
+}
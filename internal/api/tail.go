@@ -0,0 +1,98 @@
+package api
+
+import (
+	"time"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/tail"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tailBuffer    = 256
+	maxTailReplay = 1000
+)
+
+// dropNoticeInterval is how often a client that keeps falling behind is told
+// how many tasks it missed.
+var dropNoticeInterval = time.Second
+
+func (s *LoggerServer) TailLogs(req *pb.TailRequest, stream pb.AlertLogger_TailLogsServer) error {
+	filterRequest := req.GetFilter()
+	if filterRequest == nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: filter cannot be empty")
+	}
+	if err := s.validateGetLogsRequest(filterRequest); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+	if req.Replay < 0 || req.Replay > maxTailReplay {
+		return status.Errorf(codes.InvalidArgument, "Invalid request: replay must be between 0 and %d", maxTailReplay)
+	}
+
+	// Subscribe before replaying so that nothing stored in between is missed;
+	// a task stored during the replay may be sent twice.
+	subscription := s.Tail.Subscribe(query.FromTaskRequest(filterRequest), tailBuffer)
+	defer subscription.Close()
+
+	if req.Replay > 0 {
+		if err := s.replayLogs(filterRequest, int64(req.Replay), stream); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(dropNoticeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+			if err := sendDropped(subscription, stream); err != nil {
+				return err
+			}
+		case entry, ok := <-subscription.Entries():
+			if !ok {
+				return status.Errorf(codes.Unavailable, "Server is shutting down")
+			}
+
+			if err := stream.Send(&pb.TailResponse{Task: toProtoTask(entry)}); err != nil {
+				return err
+			}
+			// The tasks dropped while the buffer was full came after everything
+			// in it, so the client is told as soon as it has caught up.
+			if len(subscription.Entries()) == 0 {
+				if err := sendDropped(subscription, stream); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func sendDropped(subscription *tail.Subscription, stream pb.AlertLogger_TailLogsServer) error {
+	dropped := subscription.TakeDropped()
+	if dropped == 0 {
+		return nil
+	}
+	return stream.Send(&pb.TailResponse{Dropped: dropped})
+}
+
+func (s *LoggerServer) replayLogs(req *pb.TaskRequest, count int64, stream pb.AlertLogger_TailLogsServer) error {
+	q := store.Query{Filter: query.FromTaskRequest(req), Limit: count}
+	entries, err := s.Store.Find(stream.Context(), q)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to replay logs: %v", err)
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := stream.Send(&pb.TailResponse{Task: toProtoTask(entries[i])}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/store/memory"
+	"github.com/bondzai/logger/internal/tail"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// tailStream hands every response to the test, so each Send blocks until the
+// test reads it, like a client that reads slowly.
+type tailStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses chan *pb.TailResponse
+}
+
+func (s *tailStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tailStream) Send(response *pb.TailResponse) error {
+	select {
+	case s.responses <- response:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// startTail starts a tail with one replayed task and returns once the
+// subscription is live.
+func startTail(t *testing.T) (*tail.Hub, *tailStream, func()) {
+	logs := memory.NewLogStore()
+	document := map[string]interface{}{"task_id": float64(0), "organization": "acme", "project_id": float64(7), "type": float64(1)}
+	model.NormalizeTask(document, time.Now())
+	require.NoError(t, logs.Insert(context.Background(), document))
+
+	hub := tail.NewHub()
+	server := &LoggerServer{Store: logs, Tail: hub}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &tailStream{ctx: ctx, responses: make(chan *pb.TailResponse)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.TailLogs(&pb.TailRequest{Filter: &pb.TaskRequest{Organization: "acme", ProjectId: 7}, Replay: 1}, stream)
+	}()
+
+	replayed := <-stream.responses
+	require.Equal(t, int64(0), replayed.Task.Id, "The replay is sent after subscribing")
+	return hub, stream, func() {
+		cancel()
+		// A Send that was waiting fails with the context; either way it ends.
+		<-done
+	}
+}
+
+func tailEntries(count int) []store.Entry {
+	entries := make([]store.Entry, count)
+	for i := range entries {
+		entries[i] = store.Entry{ID: fmt.Sprint(i + 1), Task: model.Task{ID: i + 1, Organization: "acme", ProjectID: 7}}
+	}
+	return entries
+}
+
+// TestTailLogsDropNotice tests that a client that fell behind is told how
+// many tasks it missed once it has caught up, without waiting for a new task.
+func TestTailLogsDropNotice(t *testing.T) {
+	hub, stream, stop := startTail(t)
+	defer stop()
+
+	published := tailBuffer + 10
+	hub.Publish(tailEntries(published))
+
+	var tasks, dropped int64
+	for tasks+dropped < int64(published) {
+		select {
+		case response := <-stream.responses:
+			if response.Task != nil {
+				assert.Zero(t, dropped, "The notice should follow the buffered tasks")
+				assert.Zero(t, response.Dropped, "Tasks should not carry the dropped count")
+				tasks++
+				continue
+			}
+			dropped += response.Dropped
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Got %d tasks and %d dropped of %d published", tasks, dropped, published)
+		}
+	}
+	assert.Positive(t, dropped, "Tasks beyond the buffer should be reported as dropped")
+	assert.LessOrEqual(t, tasks, int64(tailBuffer+1))
+}
+
+// TestTailLogsPeriodicDropNotice tests that a client that stays behind is told
+// about dropped tasks before its backlog is drained.
+func TestTailLogsPeriodicDropNotice(t *testing.T) {
+	interval := dropNoticeInterval
+	dropNoticeInterval = time.Millisecond
+	defer func() { dropNoticeInterval = interval }()
+
+	hub, stream, stop := startTail(t)
+	defer stop()
+
+	hub.Publish(tailEntries(tailBuffer + 10))
+
+	for tasks := 0; ; tasks++ {
+		time.Sleep(2 * time.Millisecond)
+		response := <-stream.responses
+		if response.Task == nil {
+			assert.Positive(t, response.Dropped)
+			assert.Less(t, tasks, tailBuffer-1, "The notice should come while the backlog remains")
+			return
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bondzai/logger/internal/certs"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyHeader        = "x-api-key"
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// publicMethods can be called without a key, so that probes keep working.
+var publicMethods = map[string]bool{
+	healthpb.Health_Check_FullMethodName:      true,
+	healthpb.Health_Watch_FullMethodName:      true,
+	pb.AlertLogger_HealthCheck_FullMethodName: true,
+}
+
+// maxCachedKeys caps the key cache, which unknown key ids from unauthenticated
+// callers would otherwise grow without limit.
+var maxCachedKeys = 10000
+
+var audit = log.New(os.Stderr, "AUDIT: ", log.Ldate|log.Ltime|log.LUTC)
+
+// KeyStore looks up API keys by id, returning ErrKeyNotFound for unknown ids.
+type KeyStore interface {
+	Key(ctx context.Context, id string) (Key, error)
+}
+
+// tenantRequest is implemented by every request that is scoped to a tenant.
+type tenantRequest interface {
+	GetOrganization() string
+	GetProjectId() int64
+}
+
+// filteredRequest is implemented by requests that wrap a GetLogs filter, such
+// as TailRequest; their tenant is that of the filter.
+type filteredRequest interface {
+	GetFilter() *pb.TaskRequest
+}
+
+// Authenticator checks the API key of every RPC and binds the caller to the
+// organization and projects of the key. Keys are cached for cacheTTL, which is
+// therefore also how long a revocation can take to apply; unknown key ids are
+// cached as well, so that invalid tokens do not all reach the store.
+// Every RPC that is not public must name an organization and project that the
+// key allows, so a new RPC is denied until its request does.
+type Authenticator struct {
+	keys     KeyStore
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	cached map[string]cachedKey
+	// swept is when expired entries were last removed from cached.
+	swept time.Time
+}
+
+type cachedKey struct {
+	key Key
+	// err is ErrKeyNotFound for ids that do not exist.
+	err     error
+	fetched time.Time
+}
+
+type principalKey struct{}
+
+func NewAuthenticator(keys KeyStore, cacheTTL time.Duration) *Authenticator {
+	return &Authenticator{
+		keys:     keys,
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+		cached:   make(map[string]cachedKey),
+	}
+}
+
+// FromContext returns the key the current RPC was authenticated with.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(principalKey{}).(Key)
+	return key, ok
+}
+
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		key, err := a.authenticate(ctx)
+		if err == nil {
+			err = authorize(info.FullMethod, key, req)
+		}
+		if err != nil {
+			record(ctx, info.FullMethod, key, req, err)
+			return nil, err
+		}
+
+		resp, err := handler(context.WithValue(ctx, principalKey{}, key), req)
+		record(ctx, info.FullMethod, key, req, err)
+		return resp, err
+	}
+}
+
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, stream)
+		}
+
+		ctx := stream.Context()
+		key, err := a.authenticate(ctx)
+		if err != nil {
+			record(ctx, info.FullMethod, key, nil, err)
+			return err
+		}
+
+		wrapped := &authorizedStream{ServerStream: stream, ctx: context.WithValue(ctx, principalKey{}, key), method: info.FullMethod, key: key}
+		err = handler(srv, wrapped)
+		record(ctx, info.FullMethod, key, wrapped.request, err)
+		return err
+	}
+}
+
+// authorizedStream checks every request message of a stream against the key.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	method  string
+	key     Key
+	request interface{}
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.request == nil {
+		s.request = m
+	}
+	return authorize(s.method, s.key, m)
+}
+
+func (a *Authenticator) authenticate(ctx context.Context) (Key, error) {
+	token := tokenFrom(ctx)
+	if token == "" {
+		return Key{}, status.Errorf(codes.Unauthenticated, "Missing API key")
+	}
+
+	id, secret, err := parseToken(token)
+	if err != nil {
+		return Key{}, status.Errorf(codes.Unauthenticated, "Invalid API key")
+	}
+
+	key, err := a.lookup(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Key{}, status.Errorf(codes.Unauthenticated, "Invalid API key")
+	}
+	if err != nil {
+		log.Printf("Failed to look up API key: %v", err)
+		return Key{}, status.Errorf(codes.Unavailable, "Failed to verify API key")
+	}
+
+	if !key.matches(secret) {
+		return Key{}, status.Errorf(codes.Unauthenticated, "Invalid API key")
+	}
+	if err := key.Usable(a.now()); err != nil {
+		return key, status.Errorf(codes.Unauthenticated, "%v", err)
+	}
+	return key, nil
+}
+
+func (a *Authenticator) lookup(ctx context.Context, id string) (Key, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	entry, ok := a.cached[id]
+	a.mu.Unlock()
+	if ok && now.Sub(entry.fetched) < a.cacheTTL {
+		return entry.key, entry.err
+	}
+
+	key, err := a.keys.Key(ctx, id)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return Key{}, err
+	}
+
+	a.mu.Lock()
+	if now.Sub(a.swept) >= a.cacheTTL {
+		a.sweep(now)
+	}
+	if len(a.cached) < maxCachedKeys {
+		a.cached[id] = cachedKey{key: key, err: err, fetched: now}
+	}
+	a.mu.Unlock()
+	return key, err
+}
+
+// sweep removes the expired entries from the cache. a.mu must be held.
+func (a *Authenticator) sweep(now time.Time) {
+	for id, entry := range a.cached {
+		if now.Sub(entry.fetched) >= a.cacheTTL {
+			delete(a.cached, id)
+		}
+	}
+	a.swept = now
+}
+
+func authorize(method string, key Key, req interface{}) error {
+	if filtered, ok := req.(filteredRequest); ok {
+		req = filtered.GetFilter()
+	}
+
+	tenant, ok := req.(tenantRequest)
+	if !ok {
+		return status.Errorf(codes.PermissionDenied, "%s does not name a tenant that API key %s may access", method, key.ID)
+	}
+	if !key.Allows(tenant.GetOrganization(), tenant.GetProjectId()) {
+		return status.Errorf(codes.PermissionDenied, "API key %s may not access %s project %d", key.ID, tenant.GetOrganization(), tenant.GetProjectId())
+	}
+	return nil
+}
+
+func tokenFrom(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(apiKeyHeader); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get(authorizationHeader); len(values) > 0 {
+		return strings.TrimPrefix(values[0], bearerPrefix)
+	}
+	return ""
+}
+
+// record writes one audit line per RPC, including rejected ones.
+func record(ctx context.Context, method string, key Key, req interface{}, err error) {
+	if filtered, ok := req.(filteredRequest); ok {
+		req = filtered.GetFilter()
+	}
+
+	var organization string
+	var projectID int64
+	if tenant, ok := req.(tenantRequest); ok {
+		organization = tenant.GetOrganization()
+		projectID = tenant.GetProjectId()
+	}
+
+	address := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+
+	var client string
+	if identity, ok := certs.IdentityFromContext(ctx); ok {
+		client = identity.CommonName
+	}
+
+	audit.Printf("method=%s code=%s key=%q key_org=%q org=%q project=%d peer=%s client_cert=%q",
+		method, status.Code(err), key.ID, key.Organization, organization, projectID, address, client)
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type keyStore map[string]Key
+
+func (s keyStore) Key(ctx context.Context, id string) (Key, error) {
+	key, ok := s[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// countingKeyStore counts the lookups that reach the store.
+type countingKeyStore struct {
+	keyStore
+	lookups int
+}
+
+func (s *countingKeyStore) Key(ctx context.Context, id string) (Key, error) {
+	s.lookups++
+	return s.keyStore.Key(ctx, id)
+}
+
+// TestUnaryServerInterceptor tests that RPCs need a valid key bound to the
+// requested tenant.
+func TestUnaryServerInterceptor(t *testing.T) {
+	acme, acmeToken, err := NewKey("dashboards", "acme", []int64{7}, 0)
+	assert.NoError(t, err)
+	revoked, revokedToken, err := NewKey("old", "acme", nil, 0)
+	assert.NoError(t, err)
+	revoked.RevokedAt = time.Now()
+	expired, expiredToken, err := NewKey("short", "acme", nil, time.Millisecond)
+	assert.NoError(t, err)
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+
+	authenticator := NewAuthenticator(keyStore{acme.ID: acme, revoked.ID: revoked, expired.ID: expired}, time.Minute)
+	interceptor := authenticator.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: pb.AlertLogger_GetLogs_FullMethodName}
+
+	call := func(token string, req *pb.TaskRequest) (Key, codes.Code) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+		}
+		var principal Key
+		_, err := interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			principal, _ = FromContext(ctx)
+			return &pb.TaskResponse{}, nil
+		})
+		return principal, status.Code(err)
+	}
+
+	principal, code := call(acmeToken, &pb.TaskRequest{Organization: "acme", ProjectId: 7})
+	assert.Equal(t, codes.OK, code, "A key should access its own project")
+	assert.Equal(t, acme.ID, principal.ID, "Handlers should see the key")
+
+	_, code = call(acmeToken, &pb.TaskRequest{Organization: "acme", ProjectId: 8})
+	assert.Equal(t, codes.PermissionDenied, code, "A key should not access other projects")
+	_, code = call(acmeToken, &pb.TaskRequest{Organization: "globex", ProjectId: 7})
+	assert.Equal(t, codes.PermissionDenied, code, "A key should not access other organizations")
+
+	_, code = call("", &pb.TaskRequest{Organization: "acme", ProjectId: 7})
+	assert.Equal(t, codes.Unauthenticated, code, "A missing key should be rejected")
+	_, code = call(acmeToken+"x", &pb.TaskRequest{Organization: "acme", ProjectId: 7})
+	assert.Equal(t, codes.Unauthenticated, code, "A wrong secret should be rejected")
+	_, code = call(revokedToken, &pb.TaskRequest{Organization: "acme", ProjectId: 7})
+	assert.Equal(t, codes.Unauthenticated, code, "A revoked key should be rejected")
+	_, code = call(expiredToken, &pb.TaskRequest{Organization: "acme", ProjectId: 7})
+	assert.Equal(t, codes.Unauthenticated, code, "An expired key should be rejected")
+
+	_, err = interceptor(context.Background(), &pb.HealthCheckRequest{},
+		&grpc.UnaryServerInfo{FullMethod: pb.AlertLogger_HealthCheck_FullMethodName},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	assert.NoError(t, err, "Health checks should not need a key")
+}
+
+// TestAuthorizeDeniesByDefault tests that a request naming no tenant is denied
+// unless its method is public.
+func TestAuthorizeDeniesByDefault(t *testing.T) {
+	key, token, err := NewKey("dashboards", "acme", nil, 0)
+	assert.NoError(t, err)
+	interceptor := NewAuthenticator(keyStore{key.ID: key}, time.Minute).UnaryServerInterceptor()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", token))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+
+	_, err = interceptor(ctx, &pb.HealthCheckRequest{}, &grpc.UnaryServerInfo{FullMethod: "/AlertLogger/NewMethod"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "A method whose request names no tenant should be denied")
+
+	_, err = interceptor(ctx, &pb.TailRequest{}, &grpc.UnaryServerInfo{FullMethod: pb.AlertLogger_TailLogs_FullMethodName}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "A missing filter should be denied")
+
+	_, err = interceptor(ctx, &pb.HealthCheckRequest{}, &grpc.UnaryServerInfo{FullMethod: pb.AlertLogger_HealthCheck_FullMethodName}, handler)
+	assert.NoError(t, err, "Public methods should not need a tenant")
+}
+
+// TestLookupCachesUnknownKeys tests that unknown key ids are remembered for
+// the cache TTL instead of being looked up on every RPC.
+func TestLookupCachesUnknownKeys(t *testing.T) {
+	keys := &countingKeyStore{keyStore: keyStore{}}
+	authenticator := NewAuthenticator(keys, time.Minute)
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := authenticator.lookup(context.Background(), "missing")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	}
+	assert.Equal(t, 1, keys.lookups, "An unknown id should be looked up once per TTL")
+
+	key, _, err := NewKey("late", "acme", nil, 0)
+	assert.NoError(t, err)
+	key.ID = "missing"
+	keys.keyStore["missing"] = key
+	now = now.Add(time.Minute)
+	found, err := authenticator.lookup(context.Background(), "missing")
+	assert.NoError(t, err, "A key created since should be found once the TTL passed")
+	assert.Equal(t, "late", found.Name)
+	assert.Equal(t, 2, keys.lookups)
+}
+
+// TestLookupBoundsCache tests that the key cache drops expired entries and
+// stops growing at its cap however many unknown ids are tried.
+func TestLookupBoundsCache(t *testing.T) {
+	defer func(max int) { maxCachedKeys = max }(maxCachedKeys)
+	maxCachedKeys = 3
+
+	authenticator := NewAuthenticator(keyStore{}, time.Minute)
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		_, err := authenticator.lookup(context.Background(), fmt.Sprintf("unknown%d", i))
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	}
+	assert.Len(t, authenticator.cached, 3, "The cache should stop growing at its cap")
+
+	now = now.Add(time.Minute)
+	_, err := authenticator.lookup(context.Background(), "later")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Len(t, authenticator.cached, 1, "Expired entries should be swept")
+	assert.Contains(t, authenticator.cached, "later")
+}
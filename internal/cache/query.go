@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bondzai/logger/internal/redis"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	keyPrefix         = "logger:logs:"
+	invalidateTimeout = 500 * time.Millisecond
+	// generationKey is bumped to invalidate every cached response at once.
+	generationKey = keyPrefix + "generation"
+)
+
+// QueryCache caches GetLogs responses in Redis. Entries are keyed by the
+// normalized request and by the current version of the organization/project
+// they belong to; ingesting logs bumps that version, so stale entries are
+// never read again and simply expire. Deleting logs by retention bumps it too,
+// or bumps a generation shared by all entries when a whole organization or
+// everything was affected.
+type QueryCache struct {
+	redis Store
+	ttl   time.Duration
+}
+
+// Store is the part of the Redis client that the cache uses;
+// *redis.RedisClient satisfies it.
+type Store interface {
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+	SetBytes(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	GetInt(ctx context.Context, key string) (int64, error)
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+func NewQueryCache(client Store, ttl time.Duration) *QueryCache {
+	return &QueryCache{redis: client, ttl: ttl}
+}
+
+// Get returns the cached response for req. Any Redis error is logged and
+// reported as a miss so that the cache can never fail a query.
+//
+// On a miss it also returns the key to pass to Set once the query has run.
+// The key holds the version read before the query, so a result that raced
+// with an ingest is cached under the old version and never served.
+func (c *QueryCache) Get(ctx context.Context, req *pb.TaskRequest) (*pb.TaskResponse, string, bool) {
+	key, err := c.key(ctx, req)
+	if err != nil {
+		log.Printf("Failed to build cache key: %v", err)
+		return nil, "", false
+	}
+
+	data, err := c.redis.GetBytes(ctx, key)
+	if err != nil {
+		if !redis.IsMiss(err) {
+			log.Printf("Failed to read cached logs: %v", err)
+		}
+		return nil, key, false
+	}
+
+	response := &pb.TaskResponse{}
+	if err := proto.Unmarshal(data, response); err != nil {
+		log.Printf("Failed to decode cached logs: %v", err)
+		return nil, key, false
+	}
+	return response, key, true
+}
+
+// Set caches response under key, as returned by Get. An empty key, from a Get
+// that failed, caches nothing.
+func (c *QueryCache) Set(ctx context.Context, key string, response *pb.TaskResponse) {
+	if key == "" {
+		return
+	}
+
+	data, err := proto.Marshal(response)
+	if err != nil {
+		log.Printf("Failed to encode logs for caching: %v", err)
+		return
+	}
+
+	if err := c.redis.SetBytes(ctx, key, data, c.ttl); err != nil {
+		log.Printf("Failed to cache logs: %v", err)
+	}
+}
+
+func (c *QueryCache) Invalidate(ctx context.Context, organization string, projectID int64) error {
+	_, err := c.redis.Incr(ctx, versionKey(organization, projectID))
+	return err
+}
+
+func (c *QueryCache) InvalidateAll(ctx context.Context) error {
+	_, err := c.redis.Incr(ctx, generationKey)
+	return err
+}
+
+// InvalidateDeleted drops the cached responses that may contain logs deleted
+// from organization and project. A zero projectID stands for every project of
+// the organization and an empty organization for all of them. It is meant to
+// be registered as a retention listener.
+func (c *QueryCache) InvalidateDeleted(organization string, projectID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), invalidateTimeout)
+	defer cancel()
+
+	var err error
+	if organization == "" || projectID == 0 {
+		err = c.InvalidateAll(ctx)
+	} else {
+		err = c.Invalidate(ctx, organization, projectID)
+	}
+	if err != nil {
+		log.Printf("Failed to invalidate cached logs for %s/%d: %v", organization, projectID, err)
+	}
+}
+
+// InvalidateStored bumps the version of every organization/project that
+// received new documents. It is meant to be registered as a batcher listener.
+func (c *QueryCache) InvalidateStored(messages []map[string]interface{}) {
+	type scope struct {
+		organization string
+		projectID    int64
+	}
+
+	scopes := make(map[scope]struct{})
+	for _, message := range messages {
+		organization, _ := message["organization"].(string)
+		projectID, _ := message["project_id"].(float64)
+		scopes[scope{organization, int64(projectID)}] = struct{}{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), invalidateTimeout)
+	defer cancel()
+
+	for s := range scopes {
+		if err := c.Invalidate(ctx, s.organization, s.projectID); err != nil {
+			log.Printf("Failed to invalidate cached logs for %s/%d: %v", s.organization, s.projectID, err)
+		}
+	}
+}
+
+func (c *QueryCache) key(ctx context.Context, req *pb.TaskRequest) (string, error) {
+	generation, err := c.redis.GetInt(ctx, generationKey)
+	if err != nil {
+		return "", err
+	}
+	version, err := c.redis.GetInt(ctx, versionKey(req.Organization, req.ProjectId))
+	if err != nil {
+		return "", err
+	}
+
+	digest, err := normalizedDigest(req)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%sq:%s:%d:v%d.%d:%s", keyPrefix, req.Organization, req.ProjectId, generation, version, digest), nil
+}
+
+func versionKey(organization string, projectID int64) string {
+	return fmt.Sprintf("%sv:%s:%d", keyPrefix, organization, projectID)
+}
+
+// normalizedDigest hashes the request after putting set-like fields in a
+// canonical order, so that equivalent requests share a cache entry.
+func normalizedDigest(req *pb.TaskRequest) (string, error) {
+	normalized := proto.Clone(req).(*pb.TaskRequest)
+
+	sort.Slice(normalized.TaskIds, func(i, j int) bool { return normalized.TaskIds[i] < normalized.TaskIds[j] })
+	normalized.TaskIds = dedupe(normalized.TaskIds)
+	sort.Slice(normalized.Types, func(i, j int) bool { return normalized.Types[i] < normalized.Types[j] })
+	normalized.Types = dedupe(normalized.Types)
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func dedupe[T comparable](sorted []T) []T {
+	if len(sorted) == 0 {
+		return sorted
+	}
+	result := sorted[:1]
+	for _, value := range sorted[1:] {
+		if value != result[len(result)-1] {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	ints   map[string]int64
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{values: make(map[string][]byte), ints: make(map[string]int64)}
+}
+
+func (r *fakeRedis) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return nil, goredis.Nil
+	}
+	return value, nil
+}
+
+func (r *fakeRedis) SetBytes(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = value
+	return nil
+}
+
+func (r *fakeRedis) GetInt(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ints[key], nil
+}
+
+func (r *fakeRedis) Incr(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ints[key]++
+	return r.ints[key], nil
+}
+
+// TestNormalizedDigest tests that equivalent requests share a cache key and different ones do not.
+func TestNormalizedDigest(t *testing.T) {
+	base := &pb.TaskRequest{
+		Organization: "acme",
+		ProjectId:    7,
+		PageSize:     100,
+		TaskIds:      []int64{3, 1, 2},
+		Types:        []pb.TaskType{pb.TaskType(2), pb.TaskType(1)},
+	}
+	reordered := &pb.TaskRequest{
+		Organization: "acme",
+		ProjectId:    7,
+		PageSize:     100,
+		TaskIds:      []int64{1, 2, 3, 2},
+		Types:        []pb.TaskType{pb.TaskType(1), pb.TaskType(2)},
+	}
+	otherPage := &pb.TaskRequest{
+		Organization: "acme",
+		ProjectId:    7,
+		PageSize:     100,
+		PageToken:    "next",
+		TaskIds:      []int64{1, 2, 3},
+		Types:        []pb.TaskType{pb.TaskType(1), pb.TaskType(2)},
+	}
+
+	baseDigest, err := normalizedDigest(base)
+	assert.NoError(t, err)
+	reorderedDigest, err := normalizedDigest(reordered)
+	assert.NoError(t, err)
+	otherDigest, err := normalizedDigest(otherPage)
+	assert.NoError(t, err)
+
+	assert.Equal(t, baseDigest, reorderedDigest)
+	assert.NotEqual(t, baseDigest, otherDigest)
+	assert.Equal(t, []int64{3, 1, 2}, base.TaskIds, "normalizing must not modify the request")
+}
+
+// TestQueryCacheVersionRace tests that a result computed while logs were
+// ingested is not served after the ingest invalidated the cache.
+func TestQueryCacheVersionRace(t *testing.T) {
+	ctx := context.Background()
+	cache := NewQueryCache(newFakeRedis(), time.Minute)
+	req := &pb.TaskRequest{Organization: "acme", ProjectId: 7}
+	stale := &pb.TaskResponse{Tasks: []*pb.Task{{Id: 1}}}
+
+	_, key, ok := cache.Get(ctx, req)
+	require.False(t, ok)
+	require.NotEmpty(t, key, "A miss should return the key to cache the result under")
+
+	// Logs are stored while the query runs.
+	require.NoError(t, cache.Invalidate(ctx, "acme", 7))
+	cache.Set(ctx, key, stale)
+
+	_, key, ok = cache.Get(ctx, req)
+	assert.False(t, ok, "A result older than the ingest should not be served")
+
+	fresh := &pb.TaskResponse{Tasks: []*pb.Task{{Id: 1}, {Id: 2}}}
+	cache.Set(ctx, key, fresh)
+	response, _, ok := cache.Get(ctx, req)
+	require.True(t, ok, "A result newer than the ingest should be served")
+	assert.Len(t, response.Tasks, 2)
+}
+
+// TestQueryCacheInvalidateDeleted tests that deleting logs of a project, or of
+// a whole organization, stops cached results that held them from being served.
+func TestQueryCacheInvalidateDeleted(t *testing.T) {
+	ctx := context.Background()
+	cache := NewQueryCache(newFakeRedis(), time.Minute)
+	acme7 := &pb.TaskRequest{Organization: "acme", ProjectId: 7}
+	acme8 := &pb.TaskRequest{Organization: "acme", ProjectId: 8}
+	cached := func(req *pb.TaskRequest) bool {
+		_, _, ok := cache.Get(ctx, req)
+		return ok
+	}
+	fill := func(req *pb.TaskRequest) {
+		_, key, _ := cache.Get(ctx, req)
+		cache.Set(ctx, key, &pb.TaskResponse{Tasks: []*pb.Task{{Id: 1}}})
+	}
+
+	fill(acme7)
+	fill(acme8)
+	cache.InvalidateDeleted("acme", 7)
+	assert.False(t, cached(acme7), "The project logs were deleted from should be invalidated")
+	assert.True(t, cached(acme8), "Other projects should stay cached")
+
+	fill(acme7)
+	cache.InvalidateDeleted("acme", 0)
+	assert.False(t, cached(acme7), "Deleting from an organization should invalidate its projects")
+	assert.False(t, cached(acme8))
+
+	fill(acme7)
+	cache.InvalidateDeleted("", 0)
+	assert.False(t, cached(acme7), "Deleting from everything should invalidate everything")
+}
@@ -0,0 +1,129 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// forwardedCertMetadata carries the verified client certificate, DER encoded,
+// from a proxy that terminated TLS to the gRPC server behind it.
+const forwardedCertMetadata = "x-logger-client-cert-bin"
+
+// Identity describes the verified client certificate of a connection.
+type Identity struct {
+	CommonName    string
+	Organizations []string
+	DNSNames      []string
+	URIs          []string
+}
+
+type forwardedKey struct{}
+
+// IdentityFromContext returns the identity of the client certificate the
+// current RPC arrived with, if the client presented one that was verified,
+// either to this server or to the proxy that forwarded the RPC.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	if leaf, ok := ctx.Value(forwardedKey{}).(*x509.Certificate); ok {
+		return identityOf(leaf), true
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return Identity{}, false
+	}
+	leaf := verifiedLeaf(info.State)
+	if leaf == nil {
+		return Identity{}, false
+	}
+	return identityOf(leaf), true
+}
+
+// ForwardedMetadata returns the metadata that hands the verified client
+// certificate of state to a server using TrustForwardedUnary and
+// TrustForwardedStream. It is empty when no certificate was verified.
+func ForwardedMetadata(state *tls.ConnectionState) metadata.MD {
+	if state == nil {
+		return nil
+	}
+	leaf := verifiedLeaf(*state)
+	if leaf == nil {
+		return nil
+	}
+	return metadata.Pairs(forwardedCertMetadata, string(leaf.Raw))
+}
+
+// IsForwardedMetadata reports whether key is the metadata that carries a
+// forwarded certificate, so that proxies can refuse to take it from clients.
+func IsForwardedMetadata(key string) bool {
+	return key == forwardedCertMetadata
+}
+
+// TrustForwardedUnary makes certificates forwarded with ForwardedMetadata
+// visible to IdentityFromContext. Only install it on servers that nothing but
+// the proxy can reach: any caller could send the metadata.
+func TrustForwardedUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withForwarded(ctx), req)
+	}
+}
+
+// TrustForwardedStream is TrustForwardedUnary for streaming RPCs.
+func TrustForwardedStream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &forwardedStream{ServerStream: stream, ctx: withForwarded(stream.Context())})
+	}
+}
+
+type forwardedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *forwardedStream) Context() context.Context {
+	return s.ctx
+}
+
+func withForwarded(ctx context.Context) context.Context {
+	values := metadata.ValueFromIncomingContext(ctx, forwardedCertMetadata)
+	if len(values) == 0 {
+		return ctx
+	}
+
+	leaf, err := x509.ParseCertificate([]byte(values[0]))
+	if err != nil {
+		log.Printf("Failed to parse forwarded client certificate: %v", err)
+		return ctx
+	}
+	return context.WithValue(ctx, forwardedKey{}, leaf)
+}
+
+func verifiedLeaf(state tls.ConnectionState) *x509.Certificate {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+func identityOf(leaf *x509.Certificate) Identity {
+	identity := Identity{
+		CommonName:    leaf.Subject.CommonName,
+		Organizations: leaf.Subject.Organization,
+		DNSNames:      leaf.DNSNames,
+	}
+	for _, uri := range leaf.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	return identity
+}
@@ -0,0 +1,42 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TestForwardedIdentity tests that a certificate verified by a proxy reaches
+// IdentityFromContext only on servers that trust forwarded certificates.
+func TestForwardedIdentity(t *testing.T) {
+	ca := newAuthority(t)
+	clientPEM, _ := ca.issue(t, 2, "dashboard", x509.ExtKeyUsageClientAuth)
+	block, _ := pem.Decode(clientPEM)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	assert.Empty(t, ForwardedMetadata(nil), "Plaintext requests should forward nothing")
+	assert.Empty(t, ForwardedMetadata(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}), "Unverified certificates should not be forwarded")
+
+	md := ForwardedMetadata(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf, ca.cert}}})
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	_, ok := IdentityFromContext(ctx)
+	assert.False(t, ok, "Forwarded metadata alone should not be trusted")
+
+	var identity Identity
+	_, err = TrustForwardedUnary()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity, ok = IdentityFromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, ok, "A trusted forwarded certificate should yield an identity")
+	assert.Equal(t, Identity{CommonName: "dashboard", Organizations: []string{"acme"}, DNSNames: []string{"dashboard"}}, identity)
+}
@@ -0,0 +1,135 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the authority.
+func (a authority) issue(t *testing.T, serial int64, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"acme"}},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// handshake connects a client presenting clientCert to the reloader and
+// returns the state of both ends.
+func handshake(t *testing.T, r *Reloader, ca authority, clientCert tls.Certificate) (tls.ConnectionState, tls.ConnectionState) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := tls.Client(clientConn, &tls.Config{ServerName: "logger", RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+	server := tls.Server(serverConn, r.Config())
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Handshake() }()
+	require.NoError(t, client.Handshake(), "Client handshake should succeed")
+	require.NoError(t, <-errs, "Server handshake should succeed")
+	return server.ConnectionState(), client.ConnectionState()
+}
+
+// TestReloader tests mutual TLS with the loaded files and that rotated
+// certificates are picked up.
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca := newAuthority(t)
+	serverCert, serverKey := ca.issue(t, 2, "logger", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(certFile, serverCert, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, serverKey, 0o600))
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	clientPEM, clientKeyPEM := ca.issue(t, 3, "dashboard", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	_, err = NewReloader(certFile, keyFile, "", ClientAuthRequire)
+	assert.Error(t, err, "Requiring client certificates should need a CA bundle")
+
+	r, err := NewReloader(certFile, keyFile, caFile, ClientAuthRequire)
+	require.NoError(t, err, "NewReloader should load the files")
+
+	serverState, clientState := handshake(t, r, ca, clientCert)
+	assert.Equal(t, int64(2), clientState.PeerCertificates[0].SerialNumber.Int64(), "The loaded certificate should be served")
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: serverState}})
+	identity, ok := IdentityFromContext(ctx)
+	assert.True(t, ok, "A verified client certificate should yield an identity")
+	assert.Equal(t, Identity{CommonName: "dashboard", Organizations: []string{"acme"}, DNSNames: []string{"dashboard"}}, identity)
+
+	rotatedCert, rotatedKey := ca.issue(t, 4, "logger", x509.ExtKeyUsageServerAuth)
+	require.NoError(t, os.WriteFile(certFile, rotatedCert, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, rotatedKey, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	changed, err := r.changed()
+	require.NoError(t, err)
+	assert.True(t, changed, "Rewritten files should be noticed")
+	require.NoError(t, r.reload())
+
+	_, clientState = handshake(t, r, ca, clientCert)
+	assert.Equal(t, int64(4), clientState.PeerCertificates[0].SerialNumber.Int64(), "The rotated certificate should be served")
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix  = "LOGGER_"
+	configEnv  = envPrefix + "CONFIG"
+	redactMask = "[REDACTED]"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is one leaf of Config. Every leaf can be set from the config file
+// by its dotted key, from the environment as LOGGER_<KEY> and from the
+// command line as -<key>, with dots and underscores turned into dashes.
+type setting struct {
+	key    string
+	value  reflect.Value
+	secret string
+	usage  string
+}
+
+// Load builds the configuration from defaults, then the config file, then
+// environment variables, then flags, each overriding the one before.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := collect(reflect.ValueOf(cfg).Elem(), "")
+
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	path := flags.String("config", os.Getenv(configEnv), "path to a YAML or JSON config file (env "+configEnv+")")
+
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		s := s
+		usage := fmt.Sprintf("%s (default %q, env %s)", s.usage, s.display(), s.envName())
+		flags.Func(s.flagName(), usage, func(raw string) error {
+			flagValues[s.key] = raw
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+
+	if *path != "" {
+		fileValues, err := readFile(*path)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool, len(settings))
+		for _, s := range settings {
+			known[s.key] = true
+			if raw, ok := fileValues[s.key]; ok {
+				if err := s.set(raw); err != nil {
+					problems = append(problems, fmt.Sprintf("%s in %s: %v", s.key, *path, err))
+				}
+			}
+		}
+		for key := range fileValues {
+			if !known[key] {
+				problems = append(problems, fmt.Sprintf("%s in %s: unknown setting", key, *path))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := os.LookupEnv(s.envName()); ok {
+			if err := s.set(raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", s.envName(), err))
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if raw, ok := flagValues[s.key]; ok {
+			if err := s.set(raw); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: %v", s.flagName(), err))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Redacted renders the effective configuration, one setting per line, with
+// secrets masked so that it is safe to log.
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	for _, s := range collect(reflect.ValueOf(c).Elem(), "") {
+		fmt.Fprintf(&b, "%s = %s\n", s.key, s.display())
+	}
+	return b.String()
+}
+
+func collect(value reflect.Value, prefix string) []setting {
+	var settings []setting
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			settings = append(settings, collect(value.Field(i), key+".")...)
+			continue
+		}
+
+		settings = append(settings, setting{
+			key:    key,
+			value:  value.Field(i),
+			secret: field.Tag.Get("secret"),
+			usage:  field.Tag.Get("usage"),
+		})
+	}
+	return settings
+}
+
+func (s setting) envName() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+func (s setting) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case s.value.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a value like 500ms or 2s", raw)
+		}
+		s.value.SetInt(int64(duration))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Int:
+		number, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(number))
+	case s.value.Kind() == reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		s.value.SetBool(flag)
+	case s.value.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		s.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+
+	return nil
+}
+
+func (s setting) display() string {
+	var text string
+	switch value := s.value.Interface().(type) {
+	case []string:
+		text = strings.Join(value, ",")
+	default:
+		text = fmt.Sprint(value)
+	}
+
+	switch s.secret {
+	case "":
+		return text
+	case "url":
+		parsed, err := url.Parse(text)
+		if err != nil {
+			return redactMask
+		}
+		return parsed.Redacted()
+	default:
+		if text == "" {
+			return text
+		}
+		return redactMask
+	}
+}
+
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	document := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &document)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	default:
+		return nil, fmt.Errorf("config file %s must end in .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten(document, "", values)
+	return values, nil
+}
+
+func flatten(document map[string]interface{}, prefix string, values map[string]string) {
+	for key, value := range document {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flatten(value, prefix+key+".", values)
+		case []interface{}:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = scalar(item)
+			}
+			values[prefix+key] = strings.Join(items, ",")
+		default:
+			values[prefix+key] = scalar(value)
+		}
+	}
+}
+
+func scalar(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,121 @@
+// Package export encodes task logs as NDJSON or CSV. Both formats carry the
+// id of the entry followed by the fields of model.Task, named by their json
+// tags, so the columns only change when the model does.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+)
+
+// Formats accepted by NewWriter.
+const (
+	NDJSON = "ndjson"
+	CSV    = "csv"
+)
+
+// Columns is the CSV header.
+var Columns = columns()
+
+type Writer interface {
+	Write(entry store.Entry) error
+	// Flush writes out buffered rows and reports any earlier write error.
+	Flush() error
+}
+
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case NDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case CSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, want %s or %s", format, NDJSON, CSV)
+	}
+}
+
+type record struct {
+	EntryID string `json:"entry_id"`
+	model.Task
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(entry store.Entry) error {
+	entry.Task.TimeStamp = entry.Task.TimeStamp.UTC()
+	return w.encoder.Encode(record{EntryID: entry.ID, Task: entry.Task})
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(entry store.Entry) error {
+	if !w.header {
+		if err := w.writer.Write(Columns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	row := []string{entry.ID}
+	task := reflect.ValueOf(entry.Task)
+	for i := 0; i < task.NumField(); i++ {
+		row = append(row, formatField(task.Field(i)))
+	}
+	return w.writer.Write(row)
+}
+
+// Flush also writes the header of an export without rows.
+func (w *csvWriter) Flush() error {
+	if !w.header {
+		if err := w.writer.Write(Columns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func columns() []string {
+	names := []string{"entry_id"}
+	task := reflect.TypeOf(model.Task{})
+	for i := 0; i < task.NumField(); i++ {
+		names = append(names, strings.Split(task.Field(i).Tag.Get("json"), ",")[0])
+	}
+	return names
+}
+
+// formatField renders a model field as a CSV cell: times as RFC 3339 in UTC,
+// enums by name and lists joined with semicolons.
+func formatField(field reflect.Value) string {
+	switch value := field.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return value.String()
+	case []string:
+		return strings.Join(value, ";")
+	default:
+		return fmt.Sprint(value)
+	}
+}
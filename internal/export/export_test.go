@@ -0,0 +1,48 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriter tests that entries are encoded with the columns of model.Task.
+func TestWriter(t *testing.T) {
+	entry := store.Entry{ID: "65a0f1", Task: model.Task{
+		ID:           3,
+		Organization: "acme",
+		ProjectID:    7,
+		Type:         pb.TaskType_CRON,
+		Name:         "backup, nightly",
+		CronExpr:     []string{"0 2 * * *", "0 14 * * *"},
+		TimeStamp:    time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.FixedZone("ICT", 7*3600)),
+	}}
+
+	var out bytes.Buffer
+	writer, err := NewWriter(&out, CSV)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(entry))
+	require.NoError(t, writer.Flush())
+	assert.Equal(t,
+		"entry_id,task_id,organization,project_id,type,task_name,interval,task_cron_expression,disabled,timestamp\n"+
+			"65a0f1,3,acme,7,CRON,\"backup, nightly\",0,0 2 * * *;0 14 * * *,false,2024-01-01T20:04:05.006Z\n",
+		out.String(), "CSV should have a header and quote cells as needed")
+
+	out.Reset()
+	writer, err = NewWriter(&out, NDJSON)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(entry))
+	require.NoError(t, writer.Flush())
+	assert.JSONEq(t, `{"entry_id":"65a0f1","task_id":3,"organization":"acme","project_id":7,"type":2,
+		"task_name":"backup, nightly","interval":0,"task_cron_expression":["0 2 * * *","0 14 * * *"],
+		"disabled":false,"timestamp":"2024-01-01T20:04:05.006Z"}`, out.String(), "NDJSON should use the model field names")
+
+	_, err = NewWriter(&out, "xml")
+	assert.Error(t, err, "Unknown formats should be rejected")
+}
@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Services reported through grpc.health.v1. Kubernetes probes should ask for
+// LivenessService to decide on restarts and ReadinessService to decide on
+// traffic; the empty name and the AlertLogger name follow readiness.
+const (
+	LivenessService  = "liveness"
+	ReadinessService = "readiness"
+	MongoService     = "mongodb"
+	RabbitMQService  = "rabbitmq"
+
+	defaultInterval = 5 * time.Second
+	pingTimeout     = 2 * time.Second
+)
+
+var alertLoggerService = pb.AlertLogger_ServiceDesc.ServiceName
+
+// Pinger is the part of the Mongo client that the checker probes;
+// *mongodb.MongoDB satisfies it.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Queue is the part of the consumer that the checker probes;
+// *rabbitmq.Consumer satisfies it.
+type Queue interface {
+	Connected() bool
+	QueueDepth() (int, error)
+}
+
+type Checker struct {
+	server   *grpchealth.Server
+	mongo    Pinger
+	consumer Queue
+	interval time.Duration
+	ready    atomic.Bool
+}
+
+func NewChecker(mongo Pinger, consumer Queue, interval time.Duration) *Checker {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	checker := &Checker{
+		server:   grpchealth.NewServer(),
+		mongo:    mongo,
+		consumer: consumer,
+		interval: interval,
+	}
+
+	checker.server.SetServingStatus(LivenessService, healthpb.HealthCheckResponse_SERVING)
+	checker.setReady(false)
+	return checker
+}
+
+func (c *Checker) Register(server *grpc.Server) {
+	healthpb.RegisterHealthServer(server, c.server)
+}
+
+// Ready reports whether every dependency passed the latest check.
+func (c *Checker) Ready() bool {
+	return c.ready.Load()
+}
+
+// Run checks the dependencies every interval until ctx is done, then marks
+// every service as not serving.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx)
+
+		select {
+		case <-ctx.Done():
+			c.server.Shutdown()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Checker) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	mongoErr := c.mongo.Ping(pingCtx)
+	consumerUp := c.consumer.Connected()
+
+	if consumerUp {
+		if depth, err := c.consumer.QueueDepth(); err == nil {
+			metrics.QueueDepth.Set(float64(depth))
+		} else {
+			log.Printf("Failed to inspect queue depth: %v", err)
+		}
+	}
+
+	c.server.SetServingStatus(MongoService, servingStatus(mongoErr == nil))
+	c.server.SetServingStatus(RabbitMQService, servingStatus(consumerUp))
+
+	ready := mongoErr == nil && consumerUp
+	if ready != c.ready.Load() {
+		log.Printf("Readiness changed to %t (mongodb error: %v, rabbitmq connected: %t)", ready, mongoErr, consumerUp)
+	}
+	c.setReady(ready)
+}
+
+func (c *Checker) setReady(ready bool) {
+	c.ready.Store(ready)
+
+	status := servingStatus(ready)
+	c.server.SetServingStatus("", status)
+	c.server.SetServingStatus(ReadinessService, status)
+	c.server.SetServingStatus(alertLoggerService, status)
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
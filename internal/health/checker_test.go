@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeMongo struct {
+	down atomic.Bool
+}
+
+func (m *fakeMongo) Ping(ctx context.Context) error {
+	if m.down.Load() {
+		return errors.New("server selection timeout")
+	}
+	return nil
+}
+
+type fakeQueue struct {
+	connected atomic.Bool
+}
+
+func (q *fakeQueue) Connected() bool {
+	return q.connected.Load()
+}
+
+func (q *fakeQueue) QueueDepth() (int, error) {
+	return 3, nil
+}
+
+func dialChecker(t *testing.T, checker *Checker) healthpb.HealthClient {
+	listener := bufconn.Listen(1 << 16)
+	server := grpc.NewServer()
+	checker.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+// TestChecker tests that readiness follows the dependencies while liveness
+// stays up, and that watchers see every change.
+func TestChecker(t *testing.T) {
+	mongo, queue := &fakeMongo{}, &fakeQueue{}
+	checker := NewChecker(mongo, queue, time.Hour)
+	client := dialChecker(t, checker)
+	ctx := context.Background()
+
+	status := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		response, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return response.Status
+	}
+
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: ReadinessService})
+	require.NoError(t, err)
+	next := func() healthpb.HealthCheckResponse_ServingStatus {
+		response, err := watch.Recv()
+		require.NoError(t, err)
+		return response.Status
+	}
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(LivenessService), "The server should be live from the start")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(""), "The server should not be ready before the first check")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, next())
+
+	checker.check(ctx)
+	assert.False(t, checker.Ready(), "A disconnected consumer should keep the server unready")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(MongoService))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(RabbitMQService))
+
+	queue.connected.Store(true)
+	checker.check(ctx)
+	assert.True(t, checker.Ready())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, next(), "Watchers should see the server become ready")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(alertLoggerService))
+
+	mongo.down.Store(true)
+	checker.check(ctx)
+	assert.False(t, checker.Ready())
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, next(), "Watchers should see Mongo going down")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(MongoService))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(LivenessService), "Dependencies should not affect liveness")
+
+	mongo.down.Store(false)
+	runCtx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		checker.Run(runCtx)
+		close(stopped)
+	}()
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, next(), "Run should check at once")
+
+	cancel()
+	<-stopped
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, next(), "Stopping should mark the server as not serving")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(LivenessService))
+}
@@ -0,0 +1,197 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/rabbitmq"
+	"github.com/bondzai/logger/internal/store"
+)
+
+const (
+	defaultBatchSize  = 500
+	defaultBatchDelay = time.Second
+)
+
+var errBatcherStopped = errors.New("batcher stopped")
+
+// flushTimeout bounds each bulk insert, so that a hung store fails the batch
+// for redelivery instead of stalling ingestion and shutdown.
+var flushTimeout = 30 * time.Second
+
+// Batcher groups deliveries into bulk inserts. A batch is written once it
+// reaches size or once its oldest delivery has waited delay, and each delivery
+// is acked only after its own document has been stored.
+type Batcher struct {
+	store      Inserter
+	size       int
+	delay      time.Duration
+	deliveries chan *rabbitmq.Delivery
+	done       chan struct{}
+	listeners  []StoredListener
+	labels     metrics.Labels
+}
+
+// Inserter is the part of a store that the batcher writes to; both
+// store.LogStore and store.ExecutionStore satisfy it.
+type Inserter interface {
+	InsertMany(ctx context.Context, documents []map[string]interface{}) error
+}
+
+// StoredListener is told about the messages of each batch that were written.
+// It runs on the batching goroutine and must not block.
+type StoredListener func(messages []map[string]interface{})
+
+func NewBatcher(inserter Inserter, size int, delay time.Duration, labels metrics.Labels) *Batcher {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if delay <= 0 {
+		delay = defaultBatchDelay
+	}
+
+	return &Batcher{
+		store:      inserter,
+		size:       size,
+		delay:      delay,
+		deliveries: make(chan *rabbitmq.Delivery, size),
+		done:       make(chan struct{}),
+		labels:     labels,
+	}
+}
+
+// OnStored registers listener; it must be called before Run.
+func (b *Batcher) OnStored(listener StoredListener) {
+	b.listeners = append(b.listeners, listener)
+}
+
+func (b *Batcher) Add(delivery *rabbitmq.Delivery) {
+	// The buffer may still have room once Run has returned, so check first.
+	select {
+	case <-b.done:
+		delivery.Fail(errBatcherStopped)
+		return
+	default:
+	}
+
+	select {
+	case b.deliveries <- delivery:
+	case <-b.done:
+		delivery.Fail(errBatcherStopped)
+	}
+}
+
+func (b *Batcher) Run(ctx context.Context) {
+	defer close(b.done)
+
+	batch := make([]*rabbitmq.Delivery, 0, b.size)
+	timer := time.NewTimer(b.delay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain(batch)
+			return
+		case delivery := <-b.deliveries:
+			if len(batch) == 0 {
+				timer.Reset(b.delay)
+			}
+			batch = append(batch, delivery)
+			if len(batch) < b.size {
+				continue
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+		}
+
+		b.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (b *Batcher) drain(batch []*rabbitmq.Delivery) {
+	for {
+		select {
+		case delivery := <-b.deliveries:
+			batch = append(batch, delivery)
+		default:
+			if len(batch) > 0 {
+				log.Printf("Flushing %d pending documents before stopping...", len(batch))
+				b.flush(batch)
+			}
+			return
+		}
+	}
+}
+
+func (b *Batcher) flush(batch []*rabbitmq.Delivery) {
+	if len(batch) == 0 {
+		return
+	}
+
+	metrics.BatchSize.Observe(float64(len(batch)))
+
+	documents := make([]map[string]interface{}, len(batch))
+	for i, delivery := range batch {
+		documents[i] = delivery.Message
+	}
+
+	// Not derived from Run's context: the final flush happens after it is done.
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	err := b.store.InsertMany(ctx, documents)
+	cancel()
+	if err == nil {
+		stored := make([]map[string]interface{}, len(batch))
+		for i, delivery := range batch {
+			delivery.Ack()
+			stored[i] = delivery.Message
+		}
+		b.notify(stored)
+		return
+	}
+
+	var insertErr *store.InsertError
+	if !errors.As(err, &insertErr) {
+		log.Printf("Failed to insert batch of %d documents: %v", len(batch), err)
+		for _, delivery := range batch {
+			delivery.Fail(err)
+		}
+		return
+	}
+
+	log.Printf("Failed to insert %d of %d documents in batch", len(insertErr.Failures), len(batch))
+	stored := make([]map[string]interface{}, 0, len(batch)-len(insertErr.Failures))
+	for i, delivery := range batch {
+		failure, failed := insertErr.Failures[i]
+		if !failed {
+			delivery.Ack()
+			stored = append(stored, delivery.Message)
+			continue
+		}
+
+		if errors.Is(failure, store.ErrRejected) {
+			failure = rabbitmq.Permanent(failure)
+		}
+		delivery.Fail(failure)
+	}
+	b.notify(stored)
+}
+
+func (b *Batcher) notify(stored []map[string]interface{}) {
+	if len(stored) == 0 {
+		return
+	}
+	for _, message := range stored {
+		organization, _ := message["organization"].(string)
+		metrics.MessagesStored.WithLabelValues(b.labels.Organization(organization)).Inc()
+	}
+	for _, listener := range b.listeners {
+		listener(stored)
+	}
+}
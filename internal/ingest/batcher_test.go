@@ -0,0 +1,238 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/rabbitmq"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeInserter struct {
+	mu      sync.Mutex
+	err     error
+	batches [][]map[string]interface{}
+}
+
+func (f *fakeInserter) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.batches = append(f.batches, documents)
+	return f.err
+}
+
+func (f *fakeInserter) all() [][]map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]map[string]interface{}(nil), f.batches...)
+}
+
+// settlements collects how each test delivery was settled; nil means acked.
+type settlements struct {
+	mu      sync.Mutex
+	results map[int]error
+}
+
+func (s *settlements) delivery(id int) *rabbitmq.Delivery {
+	return rabbitmq.NewDelivery(map[string]interface{}{"task_id": id, "organization": "acme"}, func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.results == nil {
+			s.results = make(map[int]error)
+		}
+		s.results[id] = err
+	})
+}
+
+func (s *settlements) settled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.results)
+}
+
+func (s *settlements) result(id int) (error, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err, ok := s.results[id]
+	return err, ok
+}
+
+func runBatcher(b *Batcher) (context.CancelFunc, <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(stopped)
+	}()
+	return cancel, stopped
+}
+
+// TestBatcherFlush tests that batches are written once they are full or once
+// the oldest delivery has waited the delay.
+func TestBatcherFlush(t *testing.T) {
+	inserter := &fakeInserter{}
+	batcher := NewBatcher(inserter, 3, time.Hour, metrics.Labels{})
+	var stored [][]map[string]interface{}
+	batcher.OnStored(func(messages []map[string]interface{}) { stored = append(stored, messages) })
+	cancel, stopped := runBatcher(batcher)
+
+	results := &settlements{}
+	for id := 0; id < 3; id++ {
+		batcher.Add(results.delivery(id))
+	}
+	require.Eventually(t, func() bool { return results.settled() == 3 }, time.Second, time.Millisecond)
+	require.Len(t, inserter.all(), 1, "A full batch should be written at once")
+	assert.Len(t, inserter.all()[0], 3)
+	for id := 0; id < 3; id++ {
+		err, _ := results.result(id)
+		assert.NoError(t, err, "Stored deliveries should be acked")
+	}
+	cancel()
+	<-stopped
+	require.Len(t, stored, 1, "Listeners should hear about stored messages")
+	assert.Len(t, stored[0], 3)
+
+	inserter = &fakeInserter{}
+	batcher = NewBatcher(inserter, 100, 20*time.Millisecond, metrics.Labels{})
+	cancel, stopped = runBatcher(batcher)
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	results = &settlements{}
+	start := time.Now()
+	batcher.Add(results.delivery(1))
+	require.Eventually(t, func() bool { return results.settled() == 1 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "A partial batch should wait for the delay")
+	assert.Len(t, inserter.all(), 1, "A partial batch should be written after the delay")
+}
+
+// TestBatcherFailures tests that deliveries are settled according to the
+// outcome of their own document.
+func TestBatcherFailures(t *testing.T) {
+	inserter := &fakeInserter{err: &store.InsertError{Failures: map[int]error{
+		1: fmt.Errorf("%w: duplicate key", store.ErrRejected),
+		2: errors.New("write timeout"),
+	}}}
+	batcher := NewBatcher(inserter, 3, time.Hour, metrics.Labels{})
+	var stored []map[string]interface{}
+	batcher.OnStored(func(messages []map[string]interface{}) { stored = append(stored, messages...) })
+	cancel, stopped := runBatcher(batcher)
+
+	results := &settlements{}
+	for id := 0; id < 3; id++ {
+		batcher.Add(results.delivery(id))
+	}
+	require.Eventually(t, func() bool { return results.settled() == 3 }, time.Second, time.Millisecond)
+
+	err, _ := results.result(0)
+	assert.NoError(t, err, "A stored document should be acked")
+	err, _ = results.result(1)
+	assert.True(t, rabbitmq.IsPermanent(err), "A rejected document should fail permanently")
+	assert.ErrorIs(t, err, store.ErrRejected)
+	err, _ = results.result(2)
+	assert.Error(t, err)
+	assert.False(t, rabbitmq.IsPermanent(err), "Other failures should be retried")
+
+	inserter.mu.Lock()
+	inserter.err = errors.New("connection reset")
+	inserter.mu.Unlock()
+	results = &settlements{}
+	for id := 0; id < 3; id++ {
+		batcher.Add(results.delivery(id))
+	}
+	require.Eventually(t, func() bool { return results.settled() == 3 }, time.Second, time.Millisecond)
+	for id := 0; id < 3; id++ {
+		err, _ := results.result(id)
+		assert.EqualError(t, err, "connection reset", "A failed batch should fail every delivery")
+		assert.False(t, rabbitmq.IsPermanent(err))
+	}
+
+	cancel()
+	<-stopped
+	require.Len(t, stored, 1, "Only stored messages should be reported")
+	assert.Equal(t, 0, stored[0]["task_id"])
+}
+
+// TestBatcherDrain tests that pending deliveries are written when the batcher
+// stops and that later ones are failed.
+func TestBatcherDrain(t *testing.T) {
+	inserter := &fakeInserter{}
+	batcher := NewBatcher(inserter, 100, time.Hour, metrics.Labels{})
+	cancel, stopped := runBatcher(batcher)
+
+	results := &settlements{}
+	batcher.Add(results.delivery(1))
+	batcher.Add(results.delivery(2))
+	cancel()
+	<-stopped
+
+	require.Len(t, inserter.all(), 1, "Pending deliveries should be flushed on stop")
+	assert.Len(t, inserter.all()[0], 2)
+	assert.Equal(t, 2, results.settled())
+
+	batcher.Add(results.delivery(3))
+	err, ok := results.result(3)
+	require.True(t, ok, "A delivery added after stop should be settled at once")
+	assert.ErrorIs(t, err, errBatcherStopped)
+}
+
+// hangingInserter blocks every insert until its context is done.
+type hangingInserter struct{}
+
+func (hangingInserter) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestBatcherFlushTimeout tests that a hung insert fails its batch once the
+// flush timeout passes, also when stopping.
+func TestBatcherFlushTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { flushTimeout = timeout }(flushTimeout)
+	flushTimeout = 10 * time.Millisecond
+
+	batcher := NewBatcher(hangingInserter{}, 100, time.Hour, metrics.Labels{})
+	cancel, stopped := runBatcher(batcher)
+
+	results := &settlements{}
+	batcher.Add(results.delivery(1))
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("A hung insert should not stall stopping")
+	}
+
+	err, ok := results.result(1)
+	require.True(t, ok, "The pending delivery should be settled")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, rabbitmq.IsPermanent(err), "A timed out batch should be retried")
+}
+
+// TestBatcherLabels tests that stored messages are only labelled with
+// organizations on the allow-list.
+func TestBatcherLabels(t *testing.T) {
+	batcher := NewBatcher(&fakeInserter{}, 2, time.Hour, metrics.Labels{Organizations: []string{"acme"}})
+	cancel, stopped := runBatcher(batcher)
+
+	acme := testutil.ToFloat64(metrics.MessagesStored.WithLabelValues("acme"))
+	unlisted := testutil.ToFloat64(metrics.MessagesStored.WithLabelValues(""))
+
+	results := &settlements{}
+	batcher.Add(results.delivery(1))
+	batcher.Add(rabbitmq.NewDelivery(map[string]interface{}{"task_id": 2, "organization": "random-1234"}, func(err error) {}))
+	cancel()
+	<-stopped
+
+	assert.Equal(t, acme+1, testutil.ToFloat64(metrics.MessagesStored.WithLabelValues("acme")), "A listed organization should be its own label")
+	assert.Equal(t, unlisted+1, testutil.ToFloat64(metrics.MessagesStored.WithLabelValues("")), "Other organizations should be counted unlabelled")
+}
@@ -0,0 +1,29 @@
+package ingest
+
+import (
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/rabbitmq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Normalize turns a validated message into the document that is stored: the
+// timestamp becomes a real date and the entry id is assigned up front, so that
+// listeners of the batcher see the same id as the store.
+func Normalize(next rabbitmq.MessageHandler) rabbitmq.MessageHandler {
+	return func(delivery *rabbitmq.Delivery) {
+		model.NormalizeTask(delivery.Message, time.Now())
+		delivery.Message["_id"] = primitive.NewObjectID()
+		next(delivery)
+	}
+}
+
+// NormalizeExecution is Normalize for execution events.
+func NormalizeExecution(next rabbitmq.MessageHandler) rabbitmq.MessageHandler {
+	return func(delivery *rabbitmq.Delivery) {
+		model.NormalizeExecution(delivery.Message)
+		delivery.Message["_id"] = primitive.NewObjectID()
+		next(delivery)
+	}
+}
@@ -0,0 +1,18 @@
+package ingest
+
+import (
+	"github.com/bondzai/logger/internal/rabbitmq"
+)
+
+// Route dispatches deliveries on their AMQP type property. Deliveries whose
+// type has no route go to fallback, so publishers that never set a type keep
+// working.
+func Route(routes map[string]rabbitmq.MessageHandler, fallback rabbitmq.MessageHandler) rabbitmq.MessageHandler {
+	return func(delivery *rabbitmq.Delivery) {
+		if handler, ok := routes[delivery.Type()]; ok {
+			handler(delivery)
+			return
+		}
+		fallback(delivery)
+	}
+}
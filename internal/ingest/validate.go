@@ -0,0 +1,29 @@
+package ingest
+
+import (
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/rabbitmq"
+)
+
+// Validate quarantines deliveries that do not match the Task schema and hands
+// the rest to next.
+func Validate(next rabbitmq.MessageHandler) rabbitmq.MessageHandler {
+	return func(delivery *rabbitmq.Delivery) {
+		if err := model.ValidateTask(delivery.Message); err != nil {
+			delivery.Quarantine(err)
+			return
+		}
+		next(delivery)
+	}
+}
+
+// ValidateExecution is Validate for execution events.
+func ValidateExecution(next rabbitmq.MessageHandler) rabbitmq.MessageHandler {
+	return func(delivery *rabbitmq.Delivery) {
+		if err := model.ValidateExecution(delivery.Message); err != nil {
+			delivery.Quarantine(err)
+			return
+		}
+		next(delivery)
+	}
+}
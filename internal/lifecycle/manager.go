@@ -0,0 +1,123 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const defaultStopTimeout = 15 * time.Second
+
+// Component is one long-lived part of the service. Run blocks until its
+// context is cancelled or it fails; Stop, when set, is called before that
+// context is cancelled to let the component wind down gracefully. Either may
+// be nil.
+type Component struct {
+	Name string
+	Run  func(ctx context.Context) error
+	Stop func(ctx context.Context) error
+}
+
+// Manager starts components in the order they were added and stops them in
+// reverse order, so everything a component depends on outlives it.
+type Manager struct {
+	components  []Component
+	stopTimeout time.Duration
+}
+
+type running struct {
+	component Component
+	cancel    context.CancelFunc
+	done      chan error
+}
+
+func NewManager(stopTimeout time.Duration) *Manager {
+	if stopTimeout <= 0 {
+		stopTimeout = defaultStopTimeout
+	}
+	return &Manager{stopTimeout: stopTimeout}
+}
+
+func (m *Manager) Add(component Component) {
+	m.components = append(m.components, component)
+}
+
+// Run starts every component and blocks until ctx is done or one of them
+// fails, then shuts everything down. It returns the first failure, if any.
+func (m *Manager) Run(ctx context.Context) error {
+	failures := make(chan error, len(m.components))
+	started := make([]*running, 0, len(m.components))
+
+	for _, component := range m.components {
+		componentCtx, cancel := context.WithCancel(context.Background())
+		r := &running{component: component, cancel: cancel, done: make(chan error, 1)}
+		started = append(started, r)
+
+		if component.Run == nil {
+			close(r.done)
+			continue
+		}
+
+		go func() {
+			err := r.component.Run(componentCtx)
+			if err != nil && componentCtx.Err() == nil {
+				failures <- fmt.Errorf("%s: %v", r.component.Name, err)
+			} else if componentCtx.Err() == nil {
+				failures <- fmt.Errorf("%s stopped unexpectedly", r.component.Name)
+			}
+			r.done <- err
+			close(r.done)
+		}()
+	}
+
+	var failure error
+	select {
+	case <-ctx.Done():
+		log.Println("Received termination signal. Shutting down...")
+	case failure = <-failures:
+		log.Printf("Shutting down after component failure: %v", failure)
+	}
+
+	var stopErrors []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := m.stop(started[i]); err != nil {
+			stopErrors = append(stopErrors, err)
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+	return errors.Join(stopErrors...)
+}
+
+func (m *Manager) stop(r *running) error {
+	name := r.component.Name
+	log.Printf("Stopping %s...", name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+	defer cancel()
+
+	var stopErr error
+	if r.component.Stop != nil {
+		if err := r.component.Stop(ctx); err != nil {
+			stopErr = fmt.Errorf("%s: %v", name, err)
+			log.Printf("Failed to stop %s cleanly: %v", name, err)
+		}
+	}
+
+	r.cancel()
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		log.Printf("Timed out waiting for %s to stop", name)
+		if stopErr == nil {
+			stopErr = fmt.Errorf("%s: timed out after %s", name, m.stopTimeout)
+		}
+	}
+
+	return stopErr
+}
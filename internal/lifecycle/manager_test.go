@@ -0,0 +1,100 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) component(name string) Component {
+	return Component{
+		Name: name,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			r.record(name + " exited")
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.record(name + " stopping")
+			return nil
+		},
+	}
+}
+
+// TestManagerStopsInReverseOrder tests that cancelling the context stops each
+// component, and waits for it, before moving on to the one added before it.
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	events := &recorder{}
+	manager := NewManager(time.Second)
+	manager.Add(events.component("database"))
+	manager.Add(events.component("consumer"))
+	manager.Add(events.component("server"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := manager.Run(ctx)
+	assert.NoError(t, err, "Run should not return an error on a clean shutdown")
+	assert.Equal(t, []string{
+		"server stopping", "server exited",
+		"consumer stopping", "consumer exited",
+		"database stopping", "database exited",
+	}, events.events, "Components should stop in reverse order")
+}
+
+// TestManagerShutsDownOnFailure tests that a failing component brings the
+// others down and that its error is returned.
+func TestManagerShutsDownOnFailure(t *testing.T) {
+	events := &recorder{}
+	manager := NewManager(time.Second)
+	manager.Add(events.component("database"))
+	manager.Add(Component{
+		Name: "server",
+		Run: func(ctx context.Context) error {
+			return errors.New("address already in use")
+		},
+	})
+
+	err := manager.Run(context.Background())
+	assert.EqualError(t, err, "server: address already in use")
+	assert.Equal(t, []string{"database stopping", "database exited"}, events.events, "Other components should be stopped")
+}
+
+// TestManagerStopTimeout tests that a component which ignores cancellation
+// cannot block shutdown forever.
+func TestManagerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	manager := NewManager(50 * time.Millisecond)
+	manager.Add(Component{
+		Name: "stuck",
+		Run: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := manager.Run(ctx)
+	assert.EqualError(t, err, "stuck: timed out after 50ms")
+	assert.Less(t, time.Since(start), time.Second, "Shutdown should give up after the stop timeout")
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/bondzai/logger/internal/auth"
+	pb "github.com/bondzai/logger/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// organizationRequest is implemented by every request that is scoped to a
+// tenant.
+type organizationRequest interface {
+	GetOrganization() string
+}
+
+// filteredRequest is implemented by requests that wrap a GetLogs filter.
+type filteredRequest interface {
+	GetFilter() *pb.TaskRequest
+}
+
+// Labels decides the organization label of RPCs. The interceptors must run
+// after authentication: the organization of the caller's key is used when
+// there is one. Otherwise the requested organization is only used when it is
+// one of Organizations, so that callers cannot create series at will.
+type Labels struct {
+	Organizations []string
+}
+
+func (l Labels) organization(ctx context.Context, req interface{}) string {
+	if key, ok := auth.FromContext(ctx); ok {
+		return key.Organization
+	}
+
+	return l.Organization(organizationOf(req))
+}
+
+// Organization returns organization if it is one of Organizations and the
+// empty string otherwise. Metrics about ingested data, which no key vouches
+// for, are labelled with it.
+func (l Labels) Organization(organization string) string {
+	for _, allowed := range l.Organizations {
+		if organization == allowed {
+			return organization
+		}
+	}
+	return ""
+}
+
+func UnaryServerInterceptor(labels Labels) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, labels.organization(ctx, req), start, err)
+		return resp, err
+	}
+}
+
+func StreamServerInterceptor(labels Labels) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		wrapped := &observedStream{ServerStream: stream}
+		err := handler(srv, wrapped)
+		observe(info.FullMethod, labels.organization(stream.Context(), wrapped.request), start, err)
+		return err
+	}
+}
+
+// observedStream remembers the first request message so that streaming RPCs
+// are labelled like unary ones.
+type observedStream struct {
+	grpc.ServerStream
+	request interface{}
+}
+
+func (s *observedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.request == nil {
+		s.request = m
+	}
+	return err
+}
+
+func organizationOf(req interface{}) string {
+	switch req := req.(type) {
+	case organizationRequest:
+		return req.GetOrganization()
+	case filteredRequest:
+		return req.GetFilter().GetOrganization()
+	}
+	return ""
+}
+
+func observe(method, organization string, start time.Time, err error) {
+	GRPCRequests.WithLabelValues(method, status.Code(err).String(), organization).Inc()
+	GRPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/auth"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type keyStore map[string]auth.Key
+
+func (s keyStore) Key(ctx context.Context, id string) (auth.Key, error) {
+	key, ok := s[id]
+	if !ok {
+		return auth.Key{}, auth.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// requestStream receives a single request message.
+type requestStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	request proto.Message
+}
+
+func (s *requestStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *requestStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.request)
+	return nil
+}
+
+func requests(method, code, organization string) float64 {
+	return testutil.ToFloat64(GRPCRequests.WithLabelValues(method, code, organization))
+}
+
+// TestOrganizationLabel tests that RPCs are labelled with the organization of
+// the caller's key, and without auth only with allowed organizations.
+func TestOrganizationLabel(t *testing.T) {
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	call := func(labels Labels, ctx context.Context, method string, req interface{}) {
+		_, err := UnaryServerInterceptor(labels)(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, ok)
+		require.NoError(t, err)
+	}
+
+	call(Labels{}, context.Background(), "/test/Unlisted", &pb.TaskRequest{Organization: "random-1234"})
+	assert.Equal(t, 1.0, requests("/test/Unlisted", "OK", ""), "Unlisted organizations should not become labels")
+	assert.Equal(t, 0.0, requests("/test/Unlisted", "OK", "random-1234"))
+
+	labels := Labels{Organizations: []string{"acme"}}
+	call(labels, context.Background(), "/test/Listed", &pb.TaskRequest{Organization: "acme"})
+	call(labels, context.Background(), "/test/Listed", &pb.TailRequest{Filter: &pb.TaskRequest{Organization: "acme"}})
+	assert.Equal(t, 2.0, requests("/test/Listed", "OK", "acme"), "Listed organizations should be labels")
+
+	key, token, err := auth.NewKey("dashboards", "acme", nil, 0)
+	require.NoError(t, err)
+	authenticator := auth.NewAuthenticator(keyStore{key.ID: key}, time.Minute)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return UnaryServerInterceptor(Labels{})(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test/Keyed"}, ok)
+	}
+	_, err = authenticator.UnaryServerInterceptor()(ctx, &pb.TaskRequest{Organization: "acme", ProjectId: 7}, &grpc.UnaryServerInfo{FullMethod: "/test/Keyed"}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, requests("/test/Keyed", "OK", "acme"), "The organization of the key should be the label")
+
+	streamHandler := func(srv interface{}, stream grpc.ServerStream) error {
+		return StreamServerInterceptor(Labels{})(srv, stream, &grpc.StreamServerInfo{FullMethod: "/test/KeyedStream"}, func(srv interface{}, stream grpc.ServerStream) error {
+			return stream.RecvMsg(&pb.TailRequest{})
+		})
+	}
+	stream := &requestStream{ctx: ctx, request: &pb.TailRequest{Filter: &pb.TaskRequest{Organization: "acme", ProjectId: 7}}}
+	err = authenticator.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test/KeyedStream"}, streamHandler)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, requests("/test/KeyedStream", "OK", "acme"), "Streams should be labelled like unary RPCs")
+
+	stream = &requestStream{ctx: context.Background(), request: &pb.TailRequest{Filter: &pb.TaskRequest{Organization: "random-5678"}}}
+	err = StreamServerInterceptor(labels)(nil, stream, &grpc.StreamServerInfo{FullMethod: "/test/Stream"}, func(srv interface{}, stream grpc.ServerStream) error {
+		return stream.RecvMsg(&pb.TailRequest{})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, requests("/test/Stream", "OK", ""), "Unlisted organizations should not label streams")
+}
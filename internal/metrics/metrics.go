@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "logger"
+
+var (
+	MessagesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "AMQP deliveries received from the broker.",
+	})
+
+	MessagesStored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_stored_total",
+		Help:      "Messages written to MongoDB, by organization.",
+	}, []string{"organization"})
+
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_failed_total",
+		Help:      "Message processing failures, by reason.",
+	}, []string{"reason"})
+
+	MessagesDeadLettered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_dead_lettered_total",
+		Help:      "Messages sent to the dead-letter exchange.",
+	})
+
+	MessagesQuarantined = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_quarantined_total",
+		Help:      "Messages rejected by schema validation and quarantined.",
+	})
+
+	QueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Messages ready in the consumed queue, as last reported by the broker.",
+	})
+
+	BatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "batch_size",
+		Help:      "Documents per bulk insert.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+	})
+
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_duration_seconds",
+		Help:      "Latency of MongoDB operations, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	MongoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongo_operation_errors_total",
+		Help:      "Failed MongoDB operations, by operation.",
+	}, []string{"operation"})
+
+	MissedRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "missed_runs_total",
+		Help:      "Scheduled runs that did not happen within the grace period, by organization.",
+	}, []string{"organization"})
+
+	OverdueTasks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "overdue_tasks",
+		Help:      "Enabled tasks currently past their expected run and grace period.",
+	})
+
+	RetentionDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_deleted_total",
+		Help:      "Logs deleted by the retention enforcer, by organization; empty for the default policy.",
+	}, []string{"organization"})
+
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC requests, by method, status code and organization.",
+	}, []string{"method", "code", "organization"})
+
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC request latency, by method. Streams are timed until they end.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
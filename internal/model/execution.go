@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ExecutionType is the AMQP message type publishers set on execution events;
+// messages without it are task snapshots.
+const ExecutionType = "execution"
+
+// Execution records one run of a task.
+type Execution struct {
+	TaskID       int                 `bson:"task_id" json:"task_id" validate:"required"`
+	Organization string              `bson:"organization" json:"organization" validate:"required"`
+	ProjectID    int                 `bson:"project_id" json:"project_id" validate:"required"`
+	Outcome      pb.ExecutionOutcome `bson:"outcome" json:"outcome" validate:"required"`
+	StartedAt    time.Time           `bson:"started_at" json:"started_at" validate:"required"`
+	EndedAt      time.Time           `bson:"ended_at" json:"ended_at"`
+	DurationMs   int64               `bson:"duration_ms" json:"duration_ms"`
+	Error        string              `bson:"error" json:"error"`
+	Attempt      int                 `bson:"attempt" json:"attempt"`
+	Schedule     string              `bson:"schedule" json:"schedule"`
+}
+
+// NormalizeExecution prepares a validated message for storage: the times
+// become UTC dates, the duration is derived from them when it is missing and
+// the attempt defaults to the first.
+func NormalizeExecution(message map[string]interface{}) {
+	var started, ended time.Time
+	if text, ok := message["started_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			started = parsed.UTC().Truncate(time.Millisecond)
+			message["started_at"] = started
+		}
+	}
+	if text, ok := message["ended_at"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			ended = parsed.UTC().Truncate(time.Millisecond)
+			message["ended_at"] = ended
+		}
+	}
+
+	if _, ok := message["duration_ms"]; !ok && !started.IsZero() && !ended.IsZero() {
+		message["duration_ms"] = float64(ended.Sub(started).Milliseconds())
+	}
+	if _, ok := message["attempt"]; !ok {
+		message["attempt"] = float64(1)
+	}
+}
+
+// ExecutionFromDocument decodes a stored or freshly ingested document into an
+// Execution.
+func ExecutionFromDocument(document interface{}) (Execution, error) {
+	var execution Execution
+
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return execution, err
+	}
+
+	err = bson.Unmarshal(data, &execution)
+	return execution, err
+}
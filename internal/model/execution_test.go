@@ -0,0 +1,32 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExecution tests that execution events are validated and normalized for storage.
+func TestExecution(t *testing.T) {
+	message := decode(t, `{"task_id": 1, "organization": "acme", "project_id": 7, "outcome": 2,
+		"started_at": "2024-01-03T02:00:00Z", "ended_at": "2024-01-03T02:00:01.5Z", "error": "exit status 1",
+		"schedule": "0 2 * * *"}`)
+	assert.NoError(t, ValidateExecution(message), "A complete execution should be valid")
+
+	NormalizeExecution(message)
+	execution, err := ExecutionFromDocument(message)
+	assert.NoError(t, err, "A normalized execution should decode")
+	assert.Equal(t, time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC), execution.StartedAt)
+	assert.Equal(t, int64(1500), execution.DurationMs, "Duration should be derived from the times")
+	assert.Equal(t, 1, execution.Attempt, "Attempt should default to the first")
+
+	err = ValidateExecution(decode(t, `{"task_id": 1, "organization": "acme", "project_id": 7, "outcome": 9,
+		"task_name": "backup"}`))
+	assert.Error(t, err, "A malformed execution should be rejected")
+	assert.ElementsMatch(t, []string{
+		"outcome must be one of 0 (OUTCOME_UNKNOWN), 1 (OUTCOME_SUCCESS), 2 (OUTCOME_FAILURE), 3 (OUTCOME_TIMEOUT), 4 (OUTCOME_SKIPPED)",
+		"started_at is required",
+		"task_name is not a known field",
+	}, err.(*ValidationError).Problems, "Every problem should be reported")
+}
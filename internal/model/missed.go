@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+)
+
+// MissedRun records a scheduled run of a task that was not seen within the
+// grace period after it was due.
+type MissedRun struct {
+	TaskID       int         `bson:"task_id" json:"task_id"`
+	Organization string      `bson:"organization" json:"organization"`
+	ProjectID    int         `bson:"project_id" json:"project_id"`
+	Type         pb.TaskType `bson:"type" json:"type"`
+	Name         string      `bson:"task_name" json:"task_name"`
+	LastSeen     time.Time   `bson:"last_seen" json:"last_seen"`
+	ExpectedAt   time.Time   `bson:"expected_at" json:"expected_at"`
+	DetectedAt   time.Time   `bson:"detected_at" json:"detected_at"`
+}
@@ -1,15 +1,46 @@
 package model
 
-import pb "github.com/bondzai/logger/proto"
+import (
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type Task struct {
-	ID           int         `bson:"task_id" json:"task_id"`
-	Organization string      `bson:"organization" json:"organization"`
-	ProjectID    int         `bson:"project_id" json:"project_id"`
-	Type         pb.TaskType `bson:"type" json:"type"`
+	ID           int         `bson:"task_id" json:"task_id" validate:"required"`
+	Organization string      `bson:"organization" json:"organization" validate:"required"`
+	ProjectID    int         `bson:"project_id" json:"project_id" validate:"required"`
+	Type         pb.TaskType `bson:"type" json:"type" validate:"required"`
 	Name         string      `bson:"task_name" json:"task_name"`
 	Interval     int64       `bson:"interval" json:"interval"`
 	CronExpr     []string    `bson:"task_cron_expression" json:"task_cron_expression"`
 	Disabled     bool        `bson:"disabled" json:"disabled"`
-	TimeStamp    string      `bson:"timestamp" json:"timestamp"`
+	TimeStamp    time.Time   `bson:"timestamp" json:"timestamp"`
+}
+
+// NormalizeTask prepares a validated message for storage. The timestamp is
+// parsed into a UTC time, truncated to the millisecond precision of BSON
+// dates, and a message without one is stamped with received.
+func NormalizeTask(message map[string]interface{}, received time.Time) {
+	stamp := received
+	if text, ok := message["timestamp"].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			stamp = parsed
+		}
+	}
+	message["timestamp"] = stamp.UTC().Truncate(time.Millisecond)
+}
+
+// TaskFromDocument decodes a stored or freshly ingested document into a Task.
+func TaskFromDocument(document interface{}) (Task, error) {
+	var task Task
+
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return task, err
+	}
+
+	err = bson.Unmarshal(data, &task)
+	return task, err
 }
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+)
+
+var timeOf = reflect.TypeOf(time.Time{})
+
+// enums maps the enum types of the models to their known values.
+var enums = map[reflect.Type]map[int32]string{
+	reflect.TypeOf(pb.TaskType(0)):         pb.TaskType_name,
+	reflect.TypeOf(pb.ExecutionOutcome(0)): pb.ExecutionOutcome_name,
+}
+
+type ValidationError struct {
+	// Subject names what was validated, such as "task".
+	Subject  string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Subject, strings.Join(e.Problems, "; "))
+}
+
+type fieldRule struct {
+	kind     reflect.Type
+	required bool
+}
+
+var (
+	taskRules      = buildRules(reflect.TypeOf(Task{}))
+	executionRules = buildRules(reflect.TypeOf(Execution{}))
+)
+
+// buildRules derives the accepted message fields from the json tags of the
+// model, so the schema cannot drift from the struct that queries decode into.
+func buildRules(model reflect.Type) map[string]fieldRule {
+	rules := make(map[string]fieldRule, model.NumField())
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		rules[name] = fieldRule{
+			kind:     field.Type,
+			required: field.Tag.Get("validate") == "required",
+		}
+	}
+	return rules
+}
+
+// ValidateTask checks a decoded JSON message against the Task schema: no
+// unknown fields, all required fields present and every value of the right
+// type.
+func ValidateTask(message map[string]interface{}) error {
+	return validate("task", taskRules, message)
+}
+
+// ValidateExecution is ValidateTask for the Execution schema.
+func ValidateExecution(message map[string]interface{}) error {
+	return validate("execution", executionRules, message)
+}
+
+func validate(subject string, rules map[string]fieldRule, message map[string]interface{}) error {
+	var problems []string
+
+	for name, rule := range rules {
+		value, ok := message[name]
+		if !ok || value == nil {
+			if rule.required {
+				problems = append(problems, fmt.Sprintf("%s is required", name))
+			}
+			continue
+		}
+		if problem := checkValue(name, rule.kind, value); problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		if rule.required && value == "" {
+			problems = append(problems, fmt.Sprintf("%s must not be empty", name))
+		}
+	}
+
+	for name := range message {
+		if _, ok := rules[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is not a known field", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &ValidationError{Subject: subject, Problems: problems}
+	}
+	return nil
+}
+
+func checkValue(name string, kind reflect.Type, value interface{}) string {
+	if values, ok := enums[kind]; ok {
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Sprintf("%s must be an integer", name)
+		}
+		if number < math.MinInt32 || number > math.MaxInt32 {
+			return fmt.Sprintf("%s must be one of %s", name, enumNames(values))
+		}
+		if _, known := values[int32(number)]; !known {
+			return fmt.Sprintf("%s must be one of %s", name, enumNames(values))
+		}
+		return ""
+	}
+
+	if kind == timeOf {
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("%s must be an RFC 3339 time string", name)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, text); err != nil {
+			return fmt.Sprintf("%s must be an RFC 3339 time string", name)
+		}
+		return ""
+	}
+
+	switch kind.Kind() {
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("%s must be a string", name)
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("%s must be a boolean", name)
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Sprintf("%s must be an integer", name)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s must be an array", name)
+		}
+		for i, item := range items {
+			if problem := checkValue(fmt.Sprintf("%s[%d]", name, i), kind.Elem(), item); problem != "" {
+				return problem
+			}
+		}
+	}
+
+	return ""
+}
+
+func enumNames(values map[int32]string) string {
+	names := make([]string, 0, len(values))
+	for value := int32(0); value < int32(len(values)); value++ {
+		names = append(names, fmt.Sprintf("%d (%s)", value, values[value]))
+	}
+	return strings.Join(names, ", ")
+}
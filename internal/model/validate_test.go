@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, body string) map[string]interface{} {
+	message := make(map[string]interface{})
+	err := json.Unmarshal([]byte(body), &message)
+	assert.NoError(t, err, "test message should be valid JSON")
+	return message
+}
+
+// TestValidateTask tests that messages are checked against the Task schema.
+func TestValidateTask(t *testing.T) {
+	valid := `{"task_id": 1, "organization": "acme", "project_id": 7, "type": 2,
+		"task_name": "backup", "task_cron_expression": ["0 2 * * *"], "disabled": false}`
+	assert.NoError(t, ValidateTask(decode(t, valid)), "A complete task should be valid")
+
+	err := ValidateTask(decode(t, `{"taskId": 1, "organization": "", "project_id": "7", "type": 9}`))
+	assert.Error(t, err, "A malformed task should be rejected")
+
+	validationErr, ok := err.(*ValidationError)
+	assert.True(t, ok, "Error should be a ValidationError")
+	assert.ElementsMatch(t, []string{
+		"organization must not be empty",
+		"project_id must be an integer",
+		"task_id is required",
+		"taskId is not a known field",
+		"type must be one of 0 (UNKNOWN), 1 (INTERVAL), 2 (CRON)",
+	}, validationErr.Problems, "Every problem should be reported")
+
+	err = ValidateTask(decode(t, `{"task_id": 1, "organization": "acme", "project_id": 7, "type": 1,
+		"task_cron_expression": ["ok", 3]}`))
+	assert.EqualError(t, err, "invalid task: task_cron_expression[1] must be a string")
+
+	err = ValidateTask(decode(t, `{"task_id": 1, "organization": "acme", "project_id": 7, "type": 1,
+		"timestamp": "03/01/2024 02:15"}`))
+	assert.EqualError(t, err, "invalid task: timestamp must be an RFC 3339 time string")
+}
+
+// TestNormalizeTask tests that timestamps are stored as UTC dates.
+func TestNormalizeTask(t *testing.T) {
+	received := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+
+	message := decode(t, `{"timestamp": "2024-01-03T04:15:00.123456+02:00"}`)
+	NormalizeTask(message, received)
+	assert.Equal(t, time.Date(2024, 1, 3, 2, 15, 0, 123000000, time.UTC), message["timestamp"],
+		"Timestamp should be parsed, converted to UTC and truncated to milliseconds")
+
+	message = decode(t, `{}`)
+	NormalizeTask(message, received)
+	assert.Equal(t, received, message["timestamp"], "A missing timestamp should default to the receive time")
+}
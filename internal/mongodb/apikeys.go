@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bondzai/logger/internal/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyStore keeps API keys in one collection, keyed by their id.
+type APIKeyStore struct {
+	database   *MongoDB
+	collection string
+}
+
+func NewAPIKeyStore(database *MongoDB, collection string) *APIKeyStore {
+	return &APIKeyStore{database: database, collection: collection}
+}
+
+func (s *APIKeyStore) Key(ctx context.Context, id string) (auth.Key, error) {
+	var key auth.Key
+	err := s.database.FindOne(ctx, s.collection, bson.D{{Key: "_id", Value: id}}, &key)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return key, auth.ErrKeyNotFound
+	}
+	return key, err
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, key auth.Key) error {
+	return s.database.InsertDocument(ctx, s.collection, key)
+}
+
+func (s *APIKeyStore) Revoke(ctx context.Context, id string) error {
+	matched, err := s.database.UpdateDocuments(ctx, s.collection,
+		bson.D{{Key: "_id", Value: id}},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: time.Now().UTC()}}}},
+	)
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return auth.ErrKeyNotFound
+	}
+	return nil
+}
+
+// List returns the keys of organization, or of every organization when it is
+// empty, oldest first.
+func (s *APIKeyStore) List(ctx context.Context, organization string) ([]auth.Key, error) {
+	filter := bson.D{}
+	if organization != "" {
+		filter = append(filter, bson.E{Key: "organization", Value: organization})
+	}
+
+	var keys []auth.Key
+	err := s.database.FindEach(ctx, s.collection, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}), func(document bson.Raw) error {
+		var key auth.Key
+		if err := bson.Unmarshal(document, &key); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, err
+}
@@ -0,0 +1,81 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExecutionStore is the Mongo implementation of store.ExecutionStore.
+type ExecutionStore struct {
+	database   *MongoDB
+	collection string
+}
+
+func NewExecutionStore(database *MongoDB, collection string) *ExecutionStore {
+	return &ExecutionStore{database: database, collection: collection}
+}
+
+func (s *ExecutionStore) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	return insertMany(ctx, s.database, s.collection, documents)
+}
+
+func (s *ExecutionStore) FindExecutions(ctx context.Context, q store.ExecutionQuery) ([]store.ExecutionEntry, error) {
+	filter, err := afterCursor(q.Filter.BSON(), "started_at", q.After)
+	if err != nil {
+		return nil, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetBatchSize(streamBatchSize)
+	if q.Limit > 0 {
+		findOptions.SetLimit(q.Limit)
+	}
+
+	var entries []store.ExecutionEntry
+	err = s.database.FindEach(ctx, s.collection, filter, findOptions, func(document bson.Raw) error {
+		entry, err := decodeExecution(document)
+		if err != nil {
+			log.Printf("Failed to unmarshal execution: %v", err)
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (s *ExecutionStore) LatestExecutions(ctx context.Context, filter query.ExecutionFilter) ([]store.ExecutionEntry, error) {
+	var entries []store.ExecutionEntry
+	err := s.database.AggregateEach(ctx, s.collection, latestPipeline(filter.BSON(), "started_at"), func(document bson.Raw) error {
+		entry, err := decodeExecution(document)
+		if err != nil {
+			log.Printf("Failed to unmarshal execution: %v", err)
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func decodeExecution(document bson.Raw) (store.ExecutionEntry, error) {
+	var entry store.ExecutionEntry
+	if err := bson.Unmarshal(document, &entry.Execution); err != nil {
+		return entry, err
+	}
+
+	id, ok := document.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return entry, errors.New("document _id is not an ObjectID")
+	}
+
+	entry.ID = id.Hex()
+	return entry, nil
+}
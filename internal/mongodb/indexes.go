@@ -0,0 +1,186 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const defaultIndexName = "_id_"
+
+// IndexSpec declares the indexes a collection should have.
+type IndexSpec struct {
+	Collection string
+	Indexes    []mongo.IndexModel
+	// Managed names indexes that are maintained elsewhere, such as the TTL
+	// index of the retention enforcer, and are therefore expected.
+	Managed []string
+}
+
+// IndexReport describes how a collection differs from its IndexSpec.
+type IndexReport struct {
+	Collection string
+	Created    []string
+	// Conflicting indexes exist under a declared name with other keys; they
+	// are left alone and must be dropped by hand.
+	Conflicting []string
+	Unexpected  []string
+	// Unused indexes have not served an operation since UsageSince, which is
+	// when the server started tracking.
+	Unused     []string
+	UsageSince time.Time
+}
+
+func (r IndexReport) String() string {
+	text := fmt.Sprintf("indexes on %s: created %v, conflicting %v, unexpected %v", r.Collection, r.Created, r.Conflicting, r.Unexpected)
+	if !r.UsageSince.IsZero() {
+		text += fmt.Sprintf(", unused since %s %v", r.UsageSince.Format(time.RFC3339), r.Unused)
+	}
+	return text
+}
+
+type existingIndex struct {
+	Name string   `bson:"name"`
+	Key  bson.Raw `bson:"key"`
+}
+
+type indexStats struct {
+	Name     string `bson:"name"`
+	Accesses struct {
+		Ops   int64     `bson:"ops"`
+		Since time.Time `bson:"since"`
+	} `bson:"accesses"`
+}
+
+// ReconcileIndexes creates the declared indexes that are missing and reports
+// the ones that differ from the spec. It never drops an index. Every declared
+// index must have a name.
+func (m *MongoDB) ReconcileIndexes(ctx context.Context, spec IndexSpec) (IndexReport, error) {
+	report := IndexReport{Collection: spec.Collection}
+	collection := m.database.Collection(spec.Collection)
+
+	existing, err := m.listIndexes(ctx, collection)
+	if err != nil {
+		return report, fmt.Errorf("failed to list indexes on %s: %v", spec.Collection, err)
+	}
+
+	expected := map[string]bool{defaultIndexName: true}
+	for _, name := range spec.Managed {
+		expected[name] = true
+	}
+
+	var missing []mongo.IndexModel
+	for _, index := range spec.Indexes {
+		if index.Options == nil || index.Options.Name == nil {
+			return report, fmt.Errorf("index %v on %s has no name", index.Keys, spec.Collection)
+		}
+		keys, ok := index.Keys.(bson.D)
+		if !ok {
+			return report, fmt.Errorf("index %s on %s must declare its keys as bson.D", *index.Options.Name, spec.Collection)
+		}
+		name := *index.Options.Name
+		expected[name] = true
+
+		key, ok := existing[name]
+		switch {
+		case !ok:
+			missing = append(missing, index)
+		case !sameKeys(key, keys):
+			report.Conflicting = append(report.Conflicting, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		created, err := collection.Indexes().CreateMany(ctx, missing)
+		if err != nil {
+			return report, fmt.Errorf("failed to create indexes on %s: %v", spec.Collection, err)
+		}
+		report.Created = created
+	}
+
+	for name := range existing {
+		if !expected[name] {
+			report.Unexpected = append(report.Unexpected, name)
+		}
+	}
+
+	// Usage statistics need the indexStats privilege; without it the report
+	// simply has no unused indexes.
+	stats, err := m.indexStats(ctx, collection)
+	if err != nil {
+		log.Printf("Failed to read index usage on %s: %v", spec.Collection, err)
+		return report, nil
+	}
+	for _, stat := range stats {
+		if stat.Name == defaultIndexName || stat.Accesses.Ops > 0 {
+			continue
+		}
+		report.Unused = append(report.Unused, stat.Name)
+		if report.UsageSince.IsZero() || stat.Accesses.Since.Before(report.UsageSince) {
+			report.UsageSince = stat.Accesses.Since
+		}
+	}
+
+	return report, nil
+}
+
+func (m *MongoDB) listIndexes(ctx context.Context, collection *mongo.Collection) (map[string]bson.Raw, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []existingIndex
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bson.Raw, len(indexes))
+	for _, index := range indexes {
+		existing[index.Name] = index.Key
+	}
+	return existing, nil
+}
+
+func (m *MongoDB) indexStats(ctx context.Context, collection *mongo.Collection) ([]indexStats, error) {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{{{Key: "$indexStats", Value: bson.D{}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []indexStats
+	err = cursor.All(ctx, &stats)
+	return stats, err
+}
+
+// sameKeys compares index keys by field order and direction, ignoring the
+// numeric type the server stored the direction as.
+func sameKeys(existing bson.Raw, declared bson.D) bool {
+	elements, err := existing.Elements()
+	if err != nil || len(elements) != len(declared) {
+		return false
+	}
+
+	for i, element := range elements {
+		if element.Key() != declared[i].Key {
+			return false
+		}
+
+		value := element.Value()
+		if text, ok := value.StringValueOK(); ok {
+			if text != fmt.Sprint(declared[i].Value) {
+				return false
+			}
+			continue
+		}
+		direction, ok := value.AsInt64OK()
+		if !ok || fmt.Sprint(direction) != fmt.Sprint(declared[i].Value) {
+			return false
+		}
+	}
+	return true
+}
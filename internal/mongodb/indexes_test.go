@@ -0,0 +1,29 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func rawKeys(t *testing.T, keys bson.D) bson.Raw {
+	data, err := bson.Marshal(keys)
+	assert.NoError(t, err, "Keys should marshal")
+	return data
+}
+
+// TestSameKeys tests that index keys compare by field order and direction only.
+func TestSameKeys(t *testing.T) {
+	declared := bson.D{{Key: "organization", Value: 1}, {Key: "timestamp", Value: -1}}
+
+	assert.True(t, sameKeys(rawKeys(t, bson.D{{Key: "organization", Value: int32(1)}, {Key: "timestamp", Value: int32(-1)}}), declared))
+	assert.True(t, sameKeys(rawKeys(t, bson.D{{Key: "organization", Value: 1.0}, {Key: "timestamp", Value: -1.0}}), declared),
+		"Directions stored as doubles should match")
+	assert.False(t, sameKeys(rawKeys(t, bson.D{{Key: "timestamp", Value: -1}, {Key: "organization", Value: 1}}), declared),
+		"Field order should matter")
+	assert.False(t, sameKeys(rawKeys(t, bson.D{{Key: "organization", Value: 1}, {Key: "timestamp", Value: 1}}), declared),
+		"Direction should matter")
+	assert.False(t, sameKeys(rawKeys(t, bson.D{{Key: "organization", Value: 1}}), declared))
+	assert.True(t, sameKeys(rawKeys(t, bson.D{{Key: "task_name", Value: "text"}}), bson.D{{Key: "task_name", Value: "text"}}))
+}
@@ -0,0 +1,180 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const streamBatchSize = 1000
+
+// LogStore is the Mongo implementation of store.LogStore over one collection.
+type LogStore struct {
+	database   *MongoDB
+	collection string
+}
+
+func NewLogStore(database *MongoDB, collection string) *LogStore {
+	return &LogStore{database: database, collection: collection}
+}
+
+func (s *LogStore) Insert(ctx context.Context, document map[string]interface{}) error {
+	err := s.database.InsertDocument(ctx, s.collection, document)
+	if err != nil && IsPermanentError(err) {
+		return fmt.Errorf("%w: %v", store.ErrRejected, err)
+	}
+	return err
+}
+
+func (s *LogStore) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	return insertMany(ctx, s.database, s.collection, documents)
+}
+
+// insertMany is store.LogStore.InsertMany for any collection.
+func insertMany(ctx context.Context, database *MongoDB, collection string, documents []map[string]interface{}) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	values := make([]interface{}, len(documents))
+	for i, document := range documents {
+		values[i] = document
+	}
+
+	err := database.InsertDocuments(ctx, collection, values)
+	if err == nil {
+		return nil
+	}
+
+	failures, ok := InsertFailures(err)
+	if !ok {
+		return err
+	}
+
+	for i, failure := range failures {
+		if IsPermanentError(failure) {
+			failures[i] = fmt.Errorf("%w: %v", store.ErrRejected, failure)
+		}
+	}
+	return &store.InsertError{Failures: failures}
+}
+
+func (s *LogStore) Find(ctx context.Context, q store.Query) ([]store.Entry, error) {
+	var entries []store.Entry
+	err := s.Each(ctx, q, func(entry store.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (s *LogStore) Each(ctx context.Context, q store.Query, fn func(store.Entry) error) error {
+	filter, err := buildFilter(q)
+	if err != nil {
+		return err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetBatchSize(streamBatchSize)
+	if q.Limit > 0 {
+		findOptions.SetLimit(q.Limit)
+	}
+
+	return s.database.FindEach(ctx, s.collection, filter, findOptions, func(document bson.Raw) error {
+		entry, err := decodeEntry(document)
+		if err != nil {
+			log.Printf("Failed to unmarshal document: %v", err)
+			return nil
+		}
+		return fn(entry)
+	})
+}
+
+func (s *LogStore) Count(ctx context.Context, filter query.Filter) (int64, error) {
+	return s.database.CountDocuments(ctx, s.collection, filter.BSON())
+}
+
+func (s *LogStore) Latest(ctx context.Context, filter query.Filter) ([]store.Entry, error) {
+	var entries []store.Entry
+	err := s.database.AggregateEach(ctx, s.collection, latestPipeline(filter.BSON(), "timestamp"), func(document bson.Raw) error {
+		entry, err := decodeEntry(document)
+		if err != nil {
+			log.Printf("Failed to unmarshal document: %v", err)
+			return nil
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (s *LogStore) Delete(ctx context.Context, filter query.Filter) (int64, error) {
+	return s.database.DeleteDocuments(ctx, s.collection, filter.BSON())
+}
+
+func buildFilter(q store.Query) (bson.D, error) {
+	return afterCursor(q.Filter.BSON(), "timestamp", q.After)
+}
+
+// afterCursor narrows filter to the entries that sort after the cursor, in
+// descending order of field and then _id.
+func afterCursor(filter bson.D, field string, after *store.Cursor) (bson.D, error) {
+	if after == nil {
+		return filter, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(after.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor id: %v", err)
+	}
+
+	return append(filter, bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: "$lt", Value: after.TimeStamp}}}},
+		bson.D{
+			{Key: field, Value: after.TimeStamp},
+			{Key: "_id", Value: bson.D{{Key: "$lt", Value: id}}},
+		},
+	}}), nil
+}
+
+// latestPipeline selects the newest document by field of every task among
+// those matching filter.
+func latestPipeline(filter bson.D, field string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: field, Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "organization", Value: "$organization"},
+				{Key: "project_id", Value: "$project_id"},
+				{Key: "task_id", Value: "$task_id"},
+			}},
+			{Key: "document", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$document"}}}},
+	}
+}
+
+func decodeEntry(document bson.Raw) (store.Entry, error) {
+	var task model.Task
+	if err := bson.Unmarshal(document, &task); err != nil {
+		return store.Entry{}, err
+	}
+
+	id, ok := document.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return store.Entry{}, errors.New("document _id is not an ObjectID")
+	}
+
+	return store.Entry{ID: id.Hex(), Task: task}, nil
+}
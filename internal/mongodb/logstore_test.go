@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/store/storetest"
+)
+
+// TestLogStore runs the store conformance suite against a live MongoDB. Set
+// LOGGER_TEST_MONGO_URL to enable it.
+func TestLogStore(t *testing.T) {
+	url := os.Getenv("LOGGER_TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("LOGGER_TEST_MONGO_URL is not set")
+	}
+
+	database := NewMongoDB()
+	if err := database.Connect(url, "logger_test"); err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer database.CloseMongoDB()
+
+	storetest.Run(t, func(t *testing.T) store.LogStore {
+		collection := fmt.Sprintf("logs_%d", time.Now().UnixNano())
+		t.Cleanup(func() {
+			_ = database.database.Collection(collection).Drop(context.Background())
+		})
+		return NewLogStore(database, collection)
+	})
+}
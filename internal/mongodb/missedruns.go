@@ -0,0 +1,27 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/bondzai/logger/internal/model"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MissedRunStore records missed runs in one collection. It relies on the
+// unique index in query.MissedRunIndexes to drop duplicates.
+type MissedRunStore struct {
+	database   *MongoDB
+	collection string
+}
+
+func NewMissedRunStore(database *MongoDB, collection string) *MissedRunStore {
+	return &MissedRunStore{database: database, collection: collection}
+}
+
+func (s *MissedRunStore) RecordMissedRun(ctx context.Context, run model.MissedRun) error {
+	err := s.database.InsertDocument(ctx, s.collection, run)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
@@ -2,13 +2,21 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"log"
+	"time"
 
+	"github.com/bondzai/logger/internal/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const (
+	documentValidationFailure = 121
+	indexOptionsConflict      = 85
+)
+
 type MongoDB struct {
 	client   *mongo.Client
 	database *mongo.Database
@@ -48,24 +56,27 @@ func (m *MongoDB) CloseMongoDB() {
 	}
 }
 
-func (m *MongoDB) InsertDocument(collectionName string, document interface{}) error {
+func (m *MongoDB) InsertDocument(ctx context.Context, collectionName string, document interface{}) error {
+	start := time.Now()
 	collection := m.database.Collection(collectionName)
-	_, err := collection.InsertOne(context.Background(), document)
+	_, err := collection.InsertOne(ctx, document)
+	observe("insert_one", start, err)
 	return err
 }
 
-func (m *MongoDB) InsertDocuments(collectionName string, documents []interface{}) error {
+func (m *MongoDB) InsertDocuments(ctx context.Context, collectionName string, documents []interface{}) error {
 	collection := m.database.Collection(collectionName)
 
 	opts := options.BulkWrite().SetOrdered(false)
-	opts.SetBypassDocumentValidation(true)
 
 	bulkModels := make([]mongo.WriteModel, len(documents))
 	for i, doc := range documents {
 		bulkModels[i] = mongo.NewInsertOneModel().SetDocument(doc)
 	}
 
-	result, err := collection.BulkWrite(context.Background(), bulkModels, opts)
+	start := time.Now()
+	result, err := collection.BulkWrite(ctx, bulkModels, opts)
+	observe("bulk_insert", start, err)
 	if err != nil {
 		log.Printf("Failed to perform bulk write: %v", err)
 		return err
@@ -76,9 +87,12 @@ func (m *MongoDB) InsertDocuments(collectionName string, documents []interface{}
 	return nil
 }
 
-func (m *MongoDB) FindDocuments(collectionName string, query bson.D, findOptions *options.FindOptions) ([]interface{}, error) {
+func (m *MongoDB) FindDocuments(collectionName string, query bson.D, findOptions *options.FindOptions) (results []interface{}, err error) {
 	collection := m.database.Collection(collectionName)
 
+	start := time.Now()
+	defer func() { observe("find", start, err) }()
+
 	cursor, err := collection.Find(context.Background(), query, findOptions)
 	if err != nil {
 		log.Printf("Failed to execute find operation: %v", err)
@@ -86,20 +100,206 @@ func (m *MongoDB) FindDocuments(collectionName string, query bson.D, findOptions
 	}
 	defer cursor.Close(context.Background())
 
-	var results []interface{}
 	for cursor.Next(context.Background()) {
 		var result interface{}
-		if err := cursor.Decode(&result); err != nil {
+		if err = cursor.Decode(&result); err != nil {
 			log.Printf("Failed to decode document: %v", err)
 			return nil, err
 		}
 		results = append(results, result)
 	}
 
-	if err := cursor.Err(); err != nil {
+	if err = cursor.Err(); err != nil {
 		log.Printf("Cursor iteration error: %v", err)
 		return nil, err
 	}
 
 	return results, nil
 }
+
+func IsPermanentError(err error) bool {
+	if mongo.IsDuplicateKeyError(err) {
+		return true
+	}
+
+	var writeError mongo.WriteError
+	if errors.As(err, &writeError) {
+		return writeError.Code == documentValidationFailure
+	}
+
+	var writeException mongo.WriteException
+	if errors.As(err, &writeException) {
+		for _, writeError := range writeException.WriteErrors {
+			if writeError.Code == documentValidationFailure {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// InsertFailures extracts the per-document errors of a failed unordered bulk
+// insert, keyed by the document's index in the batch. It reports false when the
+// error is not attributable to individual documents, in which case the state of
+// the whole batch is unknown.
+func InsertFailures(err error) (map[int]error, bool) {
+	var bulkException mongo.BulkWriteException
+	if !errors.As(err, &bulkException) || bulkException.WriteConcernError != nil {
+		return nil, false
+	}
+
+	failures := make(map[int]error, len(bulkException.WriteErrors))
+	for _, writeError := range bulkException.WriteErrors {
+		failures[writeError.Index] = writeError.WriteError
+	}
+
+	return failures, true
+}
+
+func TTLIndexName(field string) string {
+	return field + "_ttl"
+}
+
+// EnsureTTLIndex creates a TTL index on field, or changes the expiry of the
+// existing one, so that documents expire ttl after the date in field.
+func (m *MongoDB) EnsureTTLIndex(ctx context.Context, collectionName, field string, ttl time.Duration) error {
+	name := TTLIndexName(field)
+	seconds := int32(ttl / time.Second)
+
+	_, err := m.database.Collection(collectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetName(name).SetExpireAfterSeconds(seconds),
+	})
+
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == indexOptionsConflict {
+		return m.database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collectionName},
+			{Key: "index", Value: bson.D{{Key: "name", Value: name}, {Key: "expireAfterSeconds", Value: seconds}}},
+		}).Err()
+	}
+	return err
+}
+
+// FindEach walks the cursor and calls fn for every document without loading
+// the whole result set into memory. Iteration stops when ctx is done or fn
+// returns an error.
+func (m *MongoDB) FindEach(ctx context.Context, collectionName string, query bson.D, findOptions *options.FindOptions, fn func(bson.Raw) error) error {
+	start := time.Now()
+	collection := m.database.Collection(collectionName)
+
+	cursor, err := collection.Find(ctx, query, findOptions)
+	// Only the first batch is timed; the rest of a stream is paced by the client.
+	observe("find_each", start, err)
+	if err != nil {
+		log.Printf("Failed to execute find operation: %v", err)
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// AggregateEach is FindEach for an aggregation pipeline.
+func (m *MongoDB) AggregateEach(ctx context.Context, collectionName string, pipeline mongo.Pipeline, fn func(bson.Raw) error) error {
+	start := time.Now()
+	collection := m.database.Collection(collectionName)
+
+	cursor, err := collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	observe("aggregate", start, err)
+	if err != nil {
+		log.Printf("Failed to execute aggregate operation: %v", err)
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	for cursor.Next(ctx) {
+		if err := fn(cursor.Current); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
+func observe(operation string, start time.Time, err error) {
+	metrics.MongoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.MongoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (m *MongoDB) CountDocuments(ctx context.Context, collectionName string, query bson.D) (count int64, err error) {
+	start := time.Now()
+	defer func() { observe("count", start, err) }()
+
+	return m.database.Collection(collectionName).CountDocuments(ctx, query)
+}
+
+func (m *MongoDB) DeleteDocuments(ctx context.Context, collectionName string, query bson.D) (deleted int64, err error) {
+	start := time.Now()
+	defer func() { observe("delete", start, err) }()
+
+	result, err := m.database.Collection(collectionName).DeleteMany(ctx, query)
+	if err != nil {
+		log.Printf("Failed to delete documents: %v", err)
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// FindOne decodes the first document matching query into result. It returns
+// mongo.ErrNoDocuments when nothing matches.
+func (m *MongoDB) FindOne(ctx context.Context, collectionName string, query bson.D, result interface{}) (err error) {
+	start := time.Now()
+	defer func() {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			observe("find_one", start, nil)
+			return
+		}
+		observe("find_one", start, err)
+	}()
+
+	return m.database.Collection(collectionName).FindOne(ctx, query).Decode(result)
+}
+
+func (m *MongoDB) UpdateDocuments(ctx context.Context, collectionName string, query bson.D, update bson.D) (matched int64, err error) {
+	start := time.Now()
+	defer func() { observe("update", start, err) }()
+
+	result, err := m.database.Collection(collectionName).UpdateMany(ctx, query, update)
+	if err != nil {
+		log.Printf("Failed to update documents: %v", err)
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+// ConvertStringTimestamps rewrites timestamps stored as RFC 3339 strings, as
+// they were before ingestion normalized them, into BSON dates. Values that do
+// not parse are left alone.
+func (m *MongoDB) ConvertStringTimestamps(ctx context.Context, collectionName string) (int64, error) {
+	filter := bson.D{{Key: "timestamp", Value: bson.D{{Key: "$type", Value: "string"}}}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "timestamp", Value: bson.D{{Key: "$convert", Value: bson.D{
+		{Key: "input", Value: "$timestamp"},
+		{Key: "to", Value: "date"},
+		{Key: "onError", Value: "$timestamp"},
+	}}}}}}}}
+
+	result, err := m.database.Collection(collectionName).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
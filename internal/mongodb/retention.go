@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/bondzai/logger/internal/retention"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RetentionBackend is the Mongo implementation of retention.Backend. Policies
+// are documents of the form
+//
+//	{organization: "acme", project_id: 7, retention_days: 365}
+//
+// where project_id is omitted for a policy covering the whole organization.
+type RetentionBackend struct {
+	database *MongoDB
+	logs     string
+	policies string
+}
+
+type policyDocument struct {
+	Organization  string `bson:"organization"`
+	ProjectID     int64  `bson:"project_id"`
+	RetentionDays int    `bson:"retention_days"`
+}
+
+func NewRetentionBackend(database *MongoDB, logs, policies string) *RetentionBackend {
+	return &RetentionBackend{database: database, logs: logs, policies: policies}
+}
+
+func (b *RetentionBackend) Policies(ctx context.Context) ([]retention.Policy, error) {
+	var policies []retention.Policy
+	err := b.database.FindEach(ctx, b.policies, bson.D{}, nil, func(raw bson.Raw) error {
+		var document policyDocument
+		if err := bson.Unmarshal(raw, &document); err != nil {
+			log.Printf("Failed to unmarshal retention policy: %v", err)
+			return nil
+		}
+		if document.Organization == "" || document.RetentionDays <= 0 {
+			log.Printf("Ignoring retention policy without organization or positive retention_days: %v", raw)
+			return nil
+		}
+
+		policies = append(policies, retention.Policy{
+			Scope:     retention.Scope{Organization: document.Organization, ProjectID: document.ProjectID},
+			Retention: time.Duration(document.RetentionDays) * 24 * time.Hour,
+		})
+		return nil
+	})
+	return policies, err
+}
+
+func (b *RetentionBackend) DeleteBefore(ctx context.Context, scope retention.Scope, exclude []retention.Scope, cutoff time.Time) (int64, error) {
+	filter := scopeFilter(scope)
+	filter = append(filter, bson.E{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: cutoff.UTC()}}})
+
+	if len(exclude) > 0 {
+		excluded := make(bson.A, len(exclude))
+		for i, other := range exclude {
+			excluded[i] = scopeFilter(other)
+		}
+		filter = append(filter, bson.E{Key: "$nor", Value: excluded})
+	}
+
+	return b.database.DeleteDocuments(ctx, b.logs, filter)
+}
+
+func (b *RetentionBackend) SetTTL(ctx context.Context, ttl time.Duration) error {
+	return b.database.EnsureTTLIndex(ctx, b.logs, "timestamp", ttl)
+}
+
+func scopeFilter(scope retention.Scope) bson.D {
+	filter := bson.D{}
+	if scope.Organization != "" {
+		filter = append(filter, bson.E{Key: "organization", Value: scope.Organization})
+	}
+	if scope.ProjectID != 0 {
+		filter = append(filter, bson.E{Key: "project_id", Value: scope.ProjectID})
+	}
+	return filter
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	pb "github.com/bondzai/logger/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ExecutionFilter is the set of conditions a GetExecutions caller can put on
+// executions. Zero values mean "no condition"; the time range applies to the
+// start of the run.
+type ExecutionFilter struct {
+	Organization string
+	ProjectID    int64
+	TaskIDs      []int64
+	Outcomes     []pb.ExecutionOutcome
+	From         time.Time
+	To           time.Time
+}
+
+func FromExecutionRequest(req *pb.ExecutionRequest) ExecutionFilter {
+	filter := ExecutionFilter{
+		Organization: req.Organization,
+		ProjectID:    req.ProjectId,
+		TaskIDs:      req.TaskIds,
+		Outcomes:     req.Outcomes,
+	}
+
+	if req.StartTime != nil {
+		filter.From = req.StartTime.AsTime()
+	}
+	if req.EndTime != nil {
+		filter.To = req.EndTime.AsTime()
+	}
+
+	return filter
+}
+
+func (f ExecutionFilter) Validate() error {
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("start time must be before end time")
+	}
+	if len(f.TaskIDs) > maxTaskIDs {
+		return fmt.Errorf("at most %d task ids can be requested", maxTaskIDs)
+	}
+	for _, outcome := range f.Outcomes {
+		if _, ok := pb.ExecutionOutcome_name[int32(outcome)]; !ok {
+			return fmt.Errorf("unknown execution outcome %d", outcome)
+		}
+	}
+	return nil
+}
+
+// BSON renders the filter as a Mongo query with the same half-open time range
+// as Filter.BSON.
+func (f ExecutionFilter) BSON() bson.D {
+	query := bson.D{}
+
+	if f.Organization != "" {
+		query = append(query, bson.E{Key: "organization", Value: f.Organization})
+	}
+
+	if f.ProjectID != 0 {
+		query = append(query, bson.E{Key: "project_id", Value: f.ProjectID})
+	}
+
+	if len(f.TaskIDs) > 0 {
+		query = append(query, bson.E{Key: "task_id", Value: bson.D{{Key: "$in", Value: f.TaskIDs}}})
+	}
+
+	if len(f.Outcomes) > 0 {
+		query = append(query, bson.E{Key: "outcome", Value: bson.D{{Key: "$in", Value: f.Outcomes}}})
+	}
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		bounds := bson.D{}
+		if !f.From.IsZero() {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: f.From.UTC()})
+		}
+		if !f.To.IsZero() {
+			bounds = append(bounds, bson.E{Key: "$lt", Value: f.To.UTC()})
+		}
+		query = append(query, bson.E{Key: "started_at", Value: bounds})
+	}
+
+	return query
+}
+
+// Match reports whether execution satisfies the filter, mirroring BSON.
+func (f ExecutionFilter) Match(execution model.Execution) bool {
+	if f.Organization != "" && execution.Organization != f.Organization {
+		return false
+	}
+	if f.ProjectID != 0 && int64(execution.ProjectID) != f.ProjectID {
+		return false
+	}
+	if len(f.TaskIDs) > 0 && !containsID(f.TaskIDs, int64(execution.TaskID)) {
+		return false
+	}
+	if len(f.Outcomes) > 0 && !containsOutcome(f.Outcomes, execution.Outcome) {
+		return false
+	}
+	if !f.From.IsZero() && execution.StartedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !execution.StartedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func containsOutcome(outcomes []pb.ExecutionOutcome, outcome pb.ExecutionOutcome) bool {
+	for _, candidate := range outcomes {
+		if candidate == outcome {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	pb "github.com/bondzai/logger/proto"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const maxTaskIDs = 1000
+
+// Filter is the set of conditions a GetLogs caller can put on tasks. Zero
+// values mean "no condition".
+type Filter struct {
+	Organization string
+	ProjectID    int64
+	TaskIDs      []int64
+	Types        []pb.TaskType
+	NameContains string
+	NamePrefix   string
+	Disabled     *bool
+	From         time.Time
+	To           time.Time
+}
+
+func FromTaskRequest(req *pb.TaskRequest) Filter {
+	filter := Filter{
+		Organization: req.Organization,
+		ProjectID:    req.ProjectId,
+		TaskIDs:      req.TaskIds,
+		Types:        req.Types,
+		NameContains: req.NameContains,
+		NamePrefix:   req.NamePrefix,
+		Disabled:     req.Disabled,
+	}
+
+	if req.StartTime != nil {
+		filter.From = req.StartTime.AsTime()
+	}
+	if req.EndTime != nil {
+		filter.To = req.EndTime.AsTime()
+	}
+
+	return filter
+}
+
+func (f Filter) Validate() error {
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return fmt.Errorf("start time must be before end time")
+	}
+	if len(f.TaskIDs) > maxTaskIDs {
+		return fmt.Errorf("at most %d task ids can be requested", maxTaskIDs)
+	}
+	for _, taskType := range f.Types {
+		if _, ok := pb.TaskType_name[int32(taskType)]; !ok {
+			return fmt.Errorf("unknown task type %d", taskType)
+		}
+	}
+	return nil
+}
+
+// BSON renders the filter as a Mongo query. The time range is half-open:
+// From is inclusive and To is exclusive.
+func (f Filter) BSON() bson.D {
+	query := bson.D{}
+
+	if f.Organization != "" {
+		query = append(query, bson.E{Key: "organization", Value: f.Organization})
+	}
+
+	if f.ProjectID != 0 {
+		query = append(query, bson.E{Key: "project_id", Value: f.ProjectID})
+	}
+
+	if len(f.TaskIDs) > 0 {
+		query = append(query, bson.E{Key: "task_id", Value: bson.D{{Key: "$in", Value: f.TaskIDs}}})
+	}
+
+	if len(f.Types) > 0 {
+		query = append(query, bson.E{Key: "type", Value: bson.D{{Key: "$in", Value: f.Types}}})
+	}
+
+	if f.NamePrefix != "" || f.NameContains != "" {
+		conditions := bson.D{}
+		if f.NamePrefix != "" {
+			conditions = append(conditions, bson.E{Key: "$regex", Value: "^" + regexp.QuoteMeta(f.NamePrefix)})
+		}
+		if f.NameContains != "" {
+			conditions = append(conditions, bson.E{Key: "$regex", Value: regexp.QuoteMeta(f.NameContains)}, bson.E{Key: "$options", Value: "i"})
+		}
+		if f.NamePrefix != "" && f.NameContains != "" {
+			// Two $regex operators cannot share one field condition.
+			query = append(query, bson.E{Key: "$and", Value: bson.A{
+				bson.D{{Key: "task_name", Value: conditions[:1]}},
+				bson.D{{Key: "task_name", Value: conditions[1:]}},
+			}})
+		} else {
+			query = append(query, bson.E{Key: "task_name", Value: conditions})
+		}
+	}
+
+	if f.Disabled != nil {
+		query = append(query, bson.E{Key: "disabled", Value: *f.Disabled})
+	}
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		bounds := bson.D{}
+		if !f.From.IsZero() {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: f.From.UTC()})
+		}
+		if !f.To.IsZero() {
+			bounds = append(bounds, bson.E{Key: "$lt", Value: f.To.UTC()})
+		}
+		query = append(query, bson.E{Key: "timestamp", Value: bounds})
+	}
+
+	return query
+}
+
+// Match reports whether task satisfies the filter, mirroring BSON for tasks
+// that are not read from Mongo.
+func (f Filter) Match(task model.Task) bool {
+	if f.Organization != "" && task.Organization != f.Organization {
+		return false
+	}
+	if f.ProjectID != 0 && int64(task.ProjectID) != f.ProjectID {
+		return false
+	}
+	if len(f.TaskIDs) > 0 && !containsID(f.TaskIDs, int64(task.ID)) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsType(f.Types, task.Type) {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(task.Name, f.NamePrefix) {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(task.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if f.Disabled != nil && task.Disabled != *f.Disabled {
+		return false
+	}
+	if !f.From.IsZero() && task.TimeStamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !task.TimeStamp.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsType(types []pb.TaskType, taskType pb.TaskType) bool {
+	for _, candidate := range types {
+		if candidate == taskType {
+			return true
+		}
+	}
+	return false
+}
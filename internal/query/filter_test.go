@@ -0,0 +1,128 @@
+package query
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestFilterBSON tests the Mongo query rendered for each kind of condition.
+func TestFilterBSON(t *testing.T) {
+	enabled := false
+	from := time.Date(2024, 1, 1, 9, 0, 0, 0, time.FixedZone("ICT", 7*60*60))
+	to := from.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bson.D
+	}{
+		{
+			name:   "empty",
+			filter: Filter{},
+			want:   bson.D{},
+		},
+		{
+			name:   "tenant",
+			filter: Filter{Organization: "acme", ProjectID: 7},
+			want:   bson.D{{Key: "organization", Value: "acme"}, {Key: "project_id", Value: int64(7)}},
+		},
+		{
+			name:   "ids and types",
+			filter: Filter{TaskIDs: []int64{1, 2}, Types: []pb.TaskType{pb.TaskType_CRON}},
+			want: bson.D{
+				{Key: "task_id", Value: bson.D{{Key: "$in", Value: []int64{1, 2}}}},
+				{Key: "type", Value: bson.D{{Key: "$in", Value: []pb.TaskType{pb.TaskType_CRON}}}},
+			},
+		},
+		{
+			name:   "prefix is anchored and quoted",
+			filter: Filter{NamePrefix: "db.backup (1)"},
+			want:   bson.D{{Key: "task_name", Value: bson.D{{Key: "$regex", Value: `^db\.backup \(1\)`}}}},
+		},
+		{
+			name:   "contains is quoted and case-insensitive",
+			filter: Filter{NameContains: "a+b*"},
+			want:   bson.D{{Key: "task_name", Value: bson.D{{Key: "$regex", Value: `a\+b\*`}, {Key: "$options", Value: "i"}}}},
+		},
+		{
+			name:   "prefix and contains",
+			filter: Filter{NamePrefix: "[x]", NameContains: "$y"},
+			want: bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "task_name", Value: bson.D{{Key: "$regex", Value: `^\[x\]`}}}},
+				bson.D{{Key: "task_name", Value: bson.D{{Key: "$regex", Value: `\$y`}, {Key: "$options", Value: "i"}}}},
+			}}},
+		},
+		{
+			name:   "disabled",
+			filter: Filter{Disabled: &enabled},
+			want:   bson.D{{Key: "disabled", Value: false}},
+		},
+		{
+			name:   "from only",
+			filter: Filter{From: from},
+			want:   bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from.UTC()}}}},
+		},
+		{
+			name:   "to only",
+			filter: Filter{To: to},
+			want:   bson.D{{Key: "timestamp", Value: bson.D{{Key: "$lt", Value: to.UTC()}}}},
+		},
+		{
+			name:   "half-open range in UTC",
+			filter: Filter{From: from, To: to},
+			want:   bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from.UTC()}, {Key: "$lt", Value: to.UTC()}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.filter.BSON())
+		})
+	}
+}
+
+// TestFilterBSONRegex tests that the rendered name patterns match names
+// literally, the way Match does.
+func TestFilterBSONRegex(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		match  []string
+		skip   []string
+	}{
+		{
+			name:   "prefix",
+			filter: Filter{NamePrefix: "a.b"},
+			match:  []string{"a.b", "a.b nightly"},
+			skip:   []string{"axb", "x a.b", "A.B"},
+		},
+		{
+			name:   "contains",
+			filter: Filter{NameContains: "(cron)*"},
+			match:  []string{"job (cron)*", "(CRON)*"},
+			skip:   []string{"cron", "(cron)"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition := test.filter.BSON()[0].Value.(bson.D)
+			pattern := condition[0].Value.(string)
+			if len(condition) > 1 {
+				pattern = "(?" + condition[1].Value.(string) + ")" + pattern
+			}
+			re := regexp.MustCompile(pattern)
+			for _, name := range test.match {
+				assert.True(t, re.MatchString(name), "%q should match", name)
+			}
+			for _, name := range test.skip {
+				assert.False(t, re.MatchString(name), "%q should not match", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package query
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LogIndexes back the GetLogs access pattern: every query is scoped to an
+// organization and project and sorted newest first, optionally narrowed by one
+// of the filters above.
+var LogIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "timestamp", Value: -1},
+			{Key: "_id", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_timestamp"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "task_id", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_task_timestamp"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "type", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_type_timestamp"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "disabled", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_disabled_timestamp"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "task_name", Value: 1},
+		},
+		Options: options.Index().SetName("organization_project_name"),
+	},
+	{
+		// Scans across tenants by time, such as warming the missed-run
+		// detector.
+		Keys: bson.D{
+			{Key: "timestamp", Value: -1},
+			{Key: "_id", Value: -1},
+		},
+		Options: options.Index().SetName("timestamp"),
+	},
+}
+
+// ExecutionIndexes back GetExecutions, which is scoped like GetLogs and sorted
+// by the start of the run.
+var ExecutionIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "started_at", Value: -1},
+			{Key: "_id", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_started"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "task_id", Value: 1},
+			{Key: "started_at", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_task_started"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "outcome", Value: 1},
+			{Key: "started_at", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_outcome_started"),
+	},
+}
+
+// MissedRunIndexes make every missed run unique, so that instances detecting
+// the same run concurrently record it once.
+var MissedRunIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+			{Key: "task_id", Value: 1},
+			{Key: "expected_at", Value: -1},
+		},
+		Options: options.Index().SetName("organization_project_task_expected").SetUnique(true),
+	},
+}
+
+// RetentionPolicyIndexes allow one policy per organization and project.
+var RetentionPolicyIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "project_id", Value: 1},
+		},
+		Options: options.Index().SetName("organization_project").SetUnique(true),
+	},
+}
+
+// APIKeyIndexes back listing the keys of an organization.
+var APIKeyIndexes = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "organization", Value: 1},
+			{Key: "created_at", Value: 1},
+		},
+		Options: options.Index().SetName("organization_created"),
+	},
+}
@@ -3,94 +3,522 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/model"
 	"github.com/streadway/amqp"
 )
 
-type MessageHandler func(message map[string]interface{}) bool
+const (
+	defaultMaxRetries        = 5
+	defaultRetryDelay        = 2 * time.Second
+	defaultReconnectDelay    = 500 * time.Millisecond
+	defaultMaxReconnectDelay = 30 * time.Second
+	defaultWorkers           = 1
+	defaultPrefetchCount     = 100
+)
+
+type MessageHandler func(delivery *Delivery)
+
+// Delivery is a decoded message waiting to be settled. Handlers must call
+// exactly one of Ack or Fail, possibly from another goroutine.
+type Delivery struct {
+	Message  map[string]interface{}
+	delivery amqp.Delivery
+	consumer *Consumer
+	// key identifies the message to the retry tracker.
+	key string
+	// settle replaces the consumer for deliveries made by NewDelivery.
+	settle func(err error)
+}
+
+// NewDelivery returns a delivery that is not backed by a broker, for handlers
+// fed from elsewhere and for tests. settle is called once with nil on Ack and
+// with the error on Fail or Quarantine.
+func NewDelivery(message map[string]interface{}, settle func(err error)) *Delivery {
+	return &Delivery{Message: message, settle: settle}
+}
+
+// Type returns the AMQP type property the publisher set, if any.
+func (d *Delivery) Type() string {
+	return d.delivery.Type
+}
+
+func (d *Delivery) Ack() {
+	if d.settle != nil {
+		d.settle(nil)
+		return
+	}
+	d.consumer.ack(d)
+}
+
+// Fail requeues the message for another attempt, or dead-letters it when err
+// is permanent or the retry budget is spent.
+func (d *Delivery) Fail(err error) {
+	if d.settle != nil {
+		d.settle(err)
+		return
+	}
+	if IsPermanent(err) {
+		metrics.MessagesFailed.WithLabelValues("permanent").Inc()
+		d.consumer.deadLetter(d, err)
+		return
+	}
+	metrics.MessagesFailed.WithLabelValues("temporary").Inc()
+	d.consumer.retry(d, err)
+}
+
+// Quarantine sets aside a message that can never be stored as is, together
+// with the reason it was rejected.
+func (d *Delivery) Quarantine(err error) {
+	if d.settle != nil {
+		d.settle(err)
+		return
+	}
+	d.consumer.quarantine(d, err)
+}
+
+type Options struct {
+	DeadLetterExchange string
+	DeadLetterQueue    string
+	QuarantineQueue    string
+	MaxRetries         int
+	RetryDelay         time.Duration
+	ReconnectDelay     time.Duration
+	MaxReconnectDelay  time.Duration
+	Workers            int
+	PrefetchCount      int
+	// OrderKey lists message fields whose values must be processed in order.
+	// Deliveries are spread freely across workers when it is empty.
+	OrderKey []string
+}
 
 type Consumer struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   amqp.Queue
+	uri       string
+	queueName string
+	options   Options
+	retries   *retryTracker
+	connected atomic.Bool
+	stopped   atomic.Bool
+	// generation counts connections; delivery tags are only unique within one.
+	generation atomic.Uint64
+
+	// dial, subscribe and after are connect, subscribeQueue and time.After;
+	// tests replace them to drive reconnects without a broker.
+	dial      func() error
+	subscribe func() (<-chan amqp.Delivery, error)
+	after     func(time.Duration) <-chan time.Time
+
+	mu         sync.RWMutex
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	queue      amqp.Queue
+	publisher  messagePublisher
+	connClosed chan *amqp.Error
+	chanClosed chan *amqp.Error
 }
 
-func NewConsumer(amqpURI, queueName string) (*Consumer, error) {
-	conn, err := amqp.Dial(amqpURI)
-	if err != nil {
+func NewConsumer(amqpURI, queueName string, options Options) (*Consumer, error) {
+	if options.MaxRetries <= 0 {
+		options.MaxRetries = defaultMaxRetries
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = defaultRetryDelay
+	}
+	if options.ReconnectDelay <= 0 {
+		options.ReconnectDelay = defaultReconnectDelay
+	}
+	if options.MaxReconnectDelay <= 0 {
+		options.MaxReconnectDelay = defaultMaxReconnectDelay
+	}
+	if options.Workers <= 0 {
+		options.Workers = defaultWorkers
+	}
+	if options.PrefetchCount <= 0 {
+		options.PrefetchCount = defaultPrefetchCount
+	}
+
+	consumer := &Consumer{
+		uri:       amqpURI,
+		queueName: queueName,
+		options:   options,
+		retries:   newRetryTracker(),
+		after:     time.After,
+	}
+	consumer.dial = consumer.connect
+	consumer.subscribe = consumer.subscribeQueue
+
+	if err := consumer.dial(); err != nil {
 		return nil, err
 	}
 
+	return consumer, nil
+}
+
+func (c *Consumer) connect() error {
+	conn, err := amqp.Dial(c.uri)
+	if err != nil {
+		return err
+	}
+
 	channel, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		_ = conn.Close()
+		return err
+	}
+
+	if err := channel.Qos(c.options.PrefetchCount, 0, false); err != nil {
+		_ = conn.Close()
+		return err
 	}
 
 	queue, err := channel.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
+		c.queueName, // name
+		true,        // durable
+		false,       // delete when unused
+		false,       // exclusive
+		false,       // no-wait
+		nil,         // arguments
 	)
 	if err != nil {
-		return nil, err
+		_ = conn.Close()
+		return err
+	}
+
+	if err := declareDeadLetter(channel, c.queueName, c.options); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	if c.options.QuarantineQueue != "" {
+		_, err := channel.QueueDeclare(c.options.QuarantineQueue, true, false, false, false, nil)
+		if err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("failed to declare quarantine queue: %v", err)
+		}
+	}
+
+	publisher, err := newPublisher(conn)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.channel = channel
+	c.queue = queue
+	c.publisher = publisher
+	c.connClosed = conn.NotifyClose(make(chan *amqp.Error, 1))
+	c.chanClosed = channel.NotifyClose(make(chan *amqp.Error, 1))
+	c.mu.Unlock()
+
+	c.generation.Add(1)
+	c.connected.Store(true)
+	log.Printf("Connected to RabbitMQ, consuming from queue %s", c.queueName)
+	return nil
+}
+
+// reconnect dials the broker until it succeeds or ctx is cancelled, waiting an
+// exponentially growing, fully jittered delay between attempts.
+func (c *Consumer) reconnect(ctx context.Context) error {
+	c.connected.Store(false)
+
+	c.mu.Lock()
+	if c.conn != nil {
+		_ = c.conn.Close()
 	}
+	c.mu.Unlock()
+
+	backoff := c.options.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		delay := time.Duration(rand.Int63n(int64(backoff))) + time.Millisecond
+		log.Printf("Reconnecting to RabbitMQ in %s (attempt %d)...", delay, attempt)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.after(delay):
+		}
+
+		err := c.dial()
+		if err == nil {
+			return nil
+		}
+		log.Printf("Failed to reconnect to RabbitMQ: %v", err)
+
+		backoff *= 2
+		if backoff > c.options.MaxReconnectDelay {
+			backoff = c.options.MaxReconnectDelay
+		}
+	}
+}
+
+func (c *Consumer) Connected() bool {
+	return c.connected.Load()
+}
+
+// QueueDepth asks the broker how many messages are waiting in the queue.
+func (c *Consumer) QueueDepth() (int, error) {
+	c.mu.RLock()
+	publisher := c.publisher
+	c.mu.RUnlock()
 
-	return &Consumer{
-		conn:    conn,
-		channel: channel,
-		queue:   queue,
-	}, nil
+	return publisher.inspect(c.queueName)
 }
 
-func (c *Consumer) Start(ctx context.Context, handler MessageHandler, wg *sync.WaitGroup) error {
-	defer wg.Done()
+func declareDeadLetter(channel *amqp.Channel, queueName string, options Options) error {
+	if options.DeadLetterExchange == "" {
+		return nil
+	}
 
-	msgs, err := c.channel.Consume(
-		c.queue.Name, // queue
-		"",           // consumer
-		true,         // auto-ack
-		false,        // exclusive
-		false,        // no-local
-		false,        // no-wait
-		nil,          // args
+	err := channel.ExchangeDeclare(
+		options.DeadLetterExchange, // name
+		amqp.ExchangeDirect,        // kind
+		true,                       // durable
+		false,                      // auto-deleted
+		false,                      // internal
+		false,                      // no-wait
+		nil,                        // arguments
 	)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange: %v", err)
+	}
+
+	if options.DeadLetterQueue == "" {
+		return nil
+	}
+
+	_, err = channel.QueueDeclare(options.DeadLetterQueue, true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue: %v", err)
+	}
+
+	err = channel.QueueBind(options.DeadLetterQueue, queueName, options.DeadLetterExchange, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to bind dead-letter queue: %v", err)
+	}
+
+	return nil
+}
+
+// Start consumes until ctx is cancelled, reconnecting whenever the broker
+// connection is lost. Deliveries already handed to workers are finished before
+// it returns; Stop must be called afterwards, once they have been settled.
+func (c *Consumer) Start(ctx context.Context, handler MessageHandler) error {
+	for {
+		err := c.consume(ctx, handler)
+		if ctx.Err() != nil || c.stopped.Load() {
+			log.Println("Received cancellation signal. Stopping consumer...")
+			return nil
+		}
+
+		log.Printf("RabbitMQ consumer interrupted: %v", err)
+		if err := c.reconnect(ctx); err != nil {
+			log.Println("Received cancellation signal while reconnecting. Stopping consumer...")
+			return nil
+		}
+	}
+}
+
+func (c *Consumer) consume(ctx context.Context, handler MessageHandler) error {
+	c.mu.RLock()
+	connClosed, chanClosed := c.connClosed, c.chanClosed
+	c.mu.RUnlock()
+
+	msgs, err := c.subscribe()
 	if err != nil {
 		return err
 	}
 
+	workers := newWorkerPool(c.options.Workers, c.options.OrderKey, handler)
+	defer workers.stop()
+
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Received cancellation signal. Stopping consumer...")
-			return nil
+			return ctx.Err()
+		case err := <-connClosed:
+			return fmt.Errorf("connection closed: %v", err)
+		case err := <-chanClosed:
+			return fmt.Errorf("channel closed: %v", err)
 		case msg, ok := <-msgs:
 			if !ok {
-				log.Println("Channel closed. Stopping consumer...")
-				return nil
+				return fmt.Errorf("delivery channel closed")
 			}
+			metrics.MessagesReceived.Inc()
 
-			message := make(map[string]interface{})
-			err := json.Unmarshal(msg.Body, &message)
-			if err != nil {
-				log.Printf("Failed to unmarshal message body: %v", err)
-				continue
+			if delivery, ok := c.decode(msg); ok {
+				workers.dispatch(delivery)
 			}
+		}
+	}
+}
 
-			if !handler(message) {
-				log.Println("Message processing failed. Stopping consumer...")
-				return nil
-			}
+func (c *Consumer) subscribeQueue() (<-chan amqp.Delivery, error) {
+	c.mu.RLock()
+	channel, queue := c.channel, c.queue
+	c.mu.RUnlock()
+
+	return channel.Consume(
+		queue.Name, // queue
+		"",         // consumer
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+}
+
+func (c *Consumer) decode(msg amqp.Delivery) (*Delivery, bool) {
+	delivery := &Delivery{delivery: msg, consumer: c, key: deliveryKey(msg, c.generation.Load())}
+
+	message := make(map[string]interface{})
+	err := json.Unmarshal(msg.Body, &message)
+	if err != nil {
+		metrics.MessagesFailed.WithLabelValues("decode").Inc()
+		c.deadLetter(delivery, fmt.Errorf("failed to unmarshal message body: %v", err))
+		return nil, false
+	}
+
+	delivery.Message = message
+	return delivery, true
+}
+
+func (c *Consumer) ack(d *Delivery) {
+	c.retries.forget(d.key)
+	if err := d.delivery.Ack(false); err != nil {
+		log.Printf("Failed to ack message: %v", err)
+	}
+}
+
+func (c *Consumer) retry(d *Delivery, cause error) {
+	msg := d.delivery
+	attempt := c.retries.increment(d.key, retryCount(msg))
+	if attempt > c.options.MaxRetries {
+		c.deadLetter(d, fmt.Errorf("giving up after %d attempts: %v", attempt, cause))
+		return
+	}
+
+	delay := c.options.RetryDelay * time.Duration(attempt)
+	log.Printf("Message processing failed (attempt %d/%d), requeueing in %s: %v", attempt, c.options.MaxRetries, delay, cause)
+
+	time.AfterFunc(delay, func() {
+		if msg.MessageId == "" {
+			// A requeued message comes back with a new delivery tag, and so
+			// with a fresh retry budget. A copy carries the count instead.
+			c.retries.forget(d.key)
+			c.divert(d, "", c.queueName, amqp.Table{retryCountHeader: int32(attempt)})
+			return
+		}
+		if err := msg.Nack(false, true); err != nil {
+			log.Printf("Failed to requeue message: %v", err)
+		}
+	})
+}
+
+func (c *Consumer) deadLetter(d *Delivery, reason error) {
+	msg := d.delivery
+	c.retries.forget(d.key)
+
+	if c.options.DeadLetterExchange == "" {
+		log.Printf("Discarding message, no dead-letter exchange configured: %v", reason)
+		if err := msg.Nack(false, false); err != nil {
+			log.Printf("Failed to reject message: %v", err)
+		}
+		return
+	}
+
+	headers := amqp.Table{"x-failure-reason": reason.Error()}
+	if c.divert(d, c.options.DeadLetterExchange, c.queueName, headers) {
+		metrics.MessagesDeadLettered.Inc()
+		log.Printf("Message dead-lettered to %s: %v", c.options.DeadLetterExchange, reason)
+	}
+}
+
+func (c *Consumer) quarantine(d *Delivery, reason error) {
+	c.retries.forget(d.key)
+
+	if c.options.QuarantineQueue == "" {
+		c.deadLetter(d, reason)
+		return
+	}
+
+	headers := amqp.Table{"x-failure-reason": reason.Error()}
+	var validationErr *model.ValidationError
+	if errors.As(reason, &validationErr) {
+		problems := make([]interface{}, len(validationErr.Problems))
+		for i, problem := range validationErr.Problems {
+			problems[i] = problem
 		}
+		headers["x-validation-errors"] = problems
+	}
+
+	if c.divert(d, "", c.options.QuarantineQueue, headers) {
+		metrics.MessagesQuarantined.Inc()
+		log.Printf("Message quarantined to %s: %v", c.options.QuarantineQueue, reason)
 	}
 }
 
+// divert republishes msg with extra headers and acks the original once the
+// broker has confirmed the copy. If the copy cannot be published the original
+// is requeued so that nothing is lost.
+func (c *Consumer) divert(d *Delivery, exchange, routingKey string, extra amqp.Table) bool {
+	msg := d.delivery
+	headers := amqp.Table{}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	for key, value := range extra {
+		headers[key] = value
+	}
+	headers["x-failed-at"] = time.Now().UTC().Format(time.RFC3339)
+	headers["x-original-queue"] = c.queueName
+
+	c.mu.RLock()
+	publisher := c.publisher
+	c.mu.RUnlock()
+
+	err := publisher.publish(exchange, routingKey, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		Body:            msg.Body,
+	})
+	if err != nil {
+		log.Printf("Failed to divert message to %s, requeueing: %v", routingKey, err)
+		if err := msg.Nack(false, true); err != nil {
+			log.Printf("Failed to requeue message: %v", err)
+		}
+		return false
+	}
+
+	if err := msg.Ack(false); err != nil {
+		log.Printf("Failed to ack diverted message: %v", err)
+	}
+	return true
+}
+
 func (c *Consumer) Stop() {
 	log.Println("Closing RabbitMQ channel and connection...")
+	c.stopped.Store(true)
+	c.connected.Store(false)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.publisher.close()
 	_ = c.channel.Close()
 	_ = c.conn.Close()
 }
@@ -0,0 +1,249 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type settlement struct {
+	tag     uint64
+	ack     bool
+	requeue bool
+}
+
+type fakeAcknowledger struct {
+	mu          sync.Mutex
+	settlements []settlement
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.record(settlement{tag: tag, ack: true})
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.record(settlement{tag: tag, requeue: requeue})
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) record(s settlement) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.settlements = append(a.settlements, s)
+}
+
+func (a *fakeAcknowledger) all() []settlement {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]settlement(nil), a.settlements...)
+}
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+type fakePublisher struct {
+	mu        sync.Mutex
+	err       error
+	published []published
+}
+
+func (p *fakePublisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func (p *fakePublisher) inspect(queueName string) (int, error) {
+	return 0, nil
+}
+
+func (p *fakePublisher) close() error {
+	return nil
+}
+
+func (p *fakePublisher) all() []published {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]published(nil), p.published...)
+}
+
+func newTestConsumer(options Options) (*Consumer, *fakeAcknowledger, *fakePublisher) {
+	if options.MaxRetries == 0 {
+		options.MaxRetries = 1
+	}
+	if options.RetryDelay == 0 {
+		options.RetryDelay = time.Millisecond
+	}
+	consumer := &Consumer{queueName: "logs", options: options, retries: newRetryTracker(), publisher: &fakePublisher{}}
+	return consumer, &fakeAcknowledger{}, consumer.publisher.(*fakePublisher)
+}
+
+func deliver(t *testing.T, c *Consumer, acknowledger *fakeAcknowledger, msg amqp.Delivery) *Delivery {
+	msg.Acknowledger = acknowledger
+	if msg.Body == nil {
+		msg.Body = []byte(`{"task_id":1}`)
+	}
+	delivery, ok := c.decode(msg)
+	require.True(t, ok, "The message should decode")
+	return delivery
+}
+
+// TestDeliverySettlement tests that every way of settling a delivery acks,
+// rejects or diverts the message as intended.
+func TestDeliverySettlement(t *testing.T) {
+	consumer, acknowledger, publisher := newTestConsumer(Options{DeadLetterExchange: "logs.dlx", QuarantineQueue: "logs.quarantine"})
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 1}).Ack()
+	assert.Equal(t, []settlement{{tag: 1, ack: true}}, acknowledger.all(), "Ack should ack the message")
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 2, MessageId: "m2"}).Fail(Permanent(errors.New("duplicate")))
+	require.Len(t, publisher.all(), 1, "A permanent failure should be dead-lettered")
+	deadLettered := publisher.all()[0]
+	assert.Equal(t, "logs.dlx", deadLettered.exchange)
+	assert.Equal(t, "logs", deadLettered.key)
+	assert.Equal(t, "duplicate", deadLettered.msg.Headers["x-failure-reason"])
+	assert.Equal(t, "m2", deadLettered.msg.MessageId, "The copy should keep the message id")
+	assert.Equal(t, settlement{tag: 2, ack: true}, acknowledger.all()[1], "The original should be acked once the copy is confirmed")
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 3}).Quarantine(&model.ValidationError{Subject: "task", Problems: []string{"task_id is required"}})
+	require.Len(t, publisher.all(), 2, "An invalid message should be quarantined")
+	quarantined := publisher.all()[1]
+	assert.Equal(t, "", quarantined.exchange)
+	assert.Equal(t, "logs.quarantine", quarantined.key)
+	assert.Equal(t, []interface{}{"task_id is required"}, quarantined.msg.Headers["x-validation-errors"])
+	assert.Equal(t, settlement{tag: 3, ack: true}, acknowledger.all()[2])
+
+	publisher.err = errors.New("channel closed")
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 4}).Fail(Permanent(errors.New("duplicate")))
+	assert.Equal(t, settlement{tag: 4, requeue: true}, acknowledger.all()[3], "A message that cannot be diverted should be requeued")
+
+	_, ok := consumer.decode(amqp.Delivery{DeliveryTag: 5, Body: []byte("{"), Acknowledger: acknowledger})
+	assert.False(t, ok, "An undecodable message should not reach the handler")
+	assert.Equal(t, settlement{tag: 5, requeue: true}, acknowledger.all()[4], "It should be dead-lettered, here requeued as publishing fails")
+
+	consumer, acknowledger, _ = newTestConsumer(Options{})
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 6}).Fail(Permanent(errors.New("duplicate")))
+	assert.Equal(t, []settlement{{tag: 6}}, acknowledger.all(), "Without a dead-letter exchange the message should be rejected")
+}
+
+// TestRetryBudget tests that retries are counted per message and that
+// messages with the same body do not share a budget.
+func TestRetryBudget(t *testing.T) {
+	consumer, acknowledger, publisher := newTestConsumer(Options{DeadLetterExchange: "logs.dlx", MaxRetries: 1})
+	body := []byte(`{"task_id":1}`)
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 1, Body: body}).Fail(errors.New("timeout"))
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 2, Body: body}).Fail(errors.New("timeout"))
+	require.Eventually(t, func() bool { return len(publisher.all()) == 2 }, time.Second, time.Millisecond)
+	for _, copy := range publisher.all() {
+		assert.Equal(t, "", copy.exchange, "Both messages should be retried, not dead-lettered")
+		assert.Equal(t, "logs", copy.key, "A message without an id should be requeued as a copy")
+		assert.Equal(t, int32(1), copy.msg.Headers[retryCountHeader], "The copy should carry the attempt count")
+	}
+
+	retried := amqp.Delivery{DeliveryTag: 3, Body: body, Headers: amqp.Table{retryCountHeader: int32(1)}}
+	deliver(t, consumer, acknowledger, retried).Fail(errors.New("timeout"))
+	require.Len(t, publisher.all(), 3)
+	assert.Equal(t, "logs.dlx", publisher.all()[2].exchange, "The copy should be dead-lettered once its budget is spent")
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 4, MessageId: "m4"}).Fail(errors.New("timeout"))
+	require.Eventually(t, func() bool { return len(acknowledger.all()) == 4 }, time.Second, time.Millisecond)
+	assert.Equal(t, settlement{tag: 4, requeue: true}, acknowledger.all()[3], "A message with an id should be requeued in place")
+
+	deliver(t, consumer, acknowledger, amqp.Delivery{DeliveryTag: 5, MessageId: "m4", Redelivered: true}).Fail(errors.New("timeout"))
+	require.Len(t, publisher.all(), 4)
+	assert.Equal(t, "logs.dlx", publisher.all()[3].exchange, "Redeliveries of the same id should share its budget")
+}
+
+// TestReconnect tests that a closed connection is redialled with a growing,
+// jittered and capped backoff and that consuming resumes afterwards.
+func TestReconnect(t *testing.T) {
+	options := Options{ReconnectDelay: 100 * time.Millisecond, MaxReconnectDelay: 400 * time.Millisecond, Workers: 1}
+	consumer, _, _ := newTestConsumer(options)
+
+	var delays []time.Duration
+	consumer.after = func(delay time.Duration) <-chan time.Time {
+		delays = append(delays, delay)
+		ready := make(chan time.Time, 1)
+		ready <- time.Time{}
+		return ready
+	}
+
+	dials := 0
+	consumer.dial = func() error {
+		dials++
+		if dials < 4 {
+			return errors.New("connection refused")
+		}
+		consumer.mu.Lock()
+		consumer.connClosed = make(chan *amqp.Error, 1)
+		consumer.chanClosed = make(chan *amqp.Error, 1)
+		consumer.mu.Unlock()
+		consumer.generation.Add(1)
+		consumer.connected.Store(true)
+		return nil
+	}
+
+	subscriptions := make(chan chan amqp.Delivery, 2)
+	consumer.subscribe = func() (<-chan amqp.Delivery, error) {
+		msgs := make(chan amqp.Delivery, 1)
+		subscriptions <- msgs
+		return msgs, nil
+	}
+
+	consumer.connClosed = make(chan *amqp.Error, 1)
+	consumer.chanClosed = make(chan *amqp.Error, 1)
+	consumer.connected.Store(true)
+
+	handled := make(chan *Delivery, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Start(ctx, func(delivery *Delivery) { handled <- delivery }) }()
+
+	<-subscriptions
+	consumer.connClosed <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restart"}
+
+	var msgs chan amqp.Delivery
+	select {
+	case msgs = <-subscriptions:
+	case <-time.After(time.Second):
+		t.Fatal("The consumer should resubscribe after reconnecting")
+	}
+	assert.True(t, consumer.Connected(), "The consumer should be connected again")
+	assert.Equal(t, 4, dials, "The broker should be dialled until it accepts")
+	require.Len(t, delays, 4)
+	for i, limit := range []time.Duration{100, 200, 400, 400} {
+		assert.GreaterOrEqual(t, delays[i], time.Millisecond, "Attempt %d should wait", i+1)
+		assert.LessOrEqual(t, delays[i], limit*time.Millisecond+time.Millisecond, "Attempt %d should wait at most the capped backoff", i+1)
+	}
+
+	msgs <- amqp.Delivery{DeliveryTag: 1, Body: []byte(`{"task_id":1}`), Acknowledger: &fakeAcknowledger{}}
+	select {
+	case delivery := <-handled:
+		assert.Equal(t, "tag:1:1", delivery.key, "Delivery tags should be scoped to the new connection")
+	case <-time.After(time.Second):
+		t.Fatal("Deliveries should be handled after resubscribing")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
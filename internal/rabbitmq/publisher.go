@@ -0,0 +1,75 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+// messagePublisher is what the consumer needs of its publishing channel.
+type messagePublisher interface {
+	publish(exchange, key string, msg amqp.Publishing) error
+	inspect(queueName string) (int, error)
+	close() error
+}
+
+type publisher struct {
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+func newPublisher(conn *amqp.Connection) (*publisher, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		_ = channel.Close()
+		return nil, err
+	}
+
+	return &publisher{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+	}, nil
+}
+
+func (p *publisher) publish(exchange, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.channel.Publish(exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	confirm, ok := <-p.confirms
+	if !ok {
+		return errors.New("publisher channel closed before confirmation")
+	}
+	if !confirm.Ack {
+		return errors.New("publish was not acknowledged by the broker")
+	}
+
+	return nil
+}
+
+func (p *publisher) close() error {
+	return p.channel.Close()
+}
+
+// inspect runs on the publisher channel because a failed passive declare
+// closes the channel it was issued on, and that must not be the consumer's.
+func (p *publisher) inspect(queueName string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	queue, err := p.channel.QueueInspect(queueName)
+	if err != nil {
+		return 0, err
+	}
+	return queue.Messages, nil
+}
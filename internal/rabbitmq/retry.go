@@ -0,0 +1,95 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not worth retrying, so the message goes straight to
+// the dead-letter exchange instead of being requeued.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// retryCountHeader carries the attempts of a message that was requeued as a
+// copy because it has no message id.
+const retryCountHeader = "x-retry-count"
+
+// retryTracker counts processing attempts per message. RabbitMQ does not track
+// redeliveries for classic queues, so the count lives here, keyed by
+// deliveryKey.
+type retryTracker struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func newRetryTracker() *retryTracker {
+	return &retryTracker{attempts: make(map[string]int)}
+}
+
+// increment records another attempt and returns the count, which starts after
+// previous attempts counted elsewhere.
+func (t *retryTracker) increment(key string, previous int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.attempts[key] < previous {
+		t.attempts[key] = previous
+	}
+	t.attempts[key]++
+	return t.attempts[key]
+}
+
+func (t *retryTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.attempts, key)
+}
+
+// deliveryKey identifies a message by the id its publisher set. Without one,
+// the delivery tag within the connection generation is used, so that distinct
+// messages with the same body never share a retry budget.
+func deliveryKey(msg amqp.Delivery, generation uint64) string {
+	if msg.MessageId != "" {
+		return "id:" + msg.MessageId
+	}
+	return fmt.Sprintf("tag:%d:%d", generation, msg.DeliveryTag)
+}
+
+func retryCount(msg amqp.Delivery) int {
+	switch count := msg.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int16:
+		return int(count)
+	case uint8:
+		return int(count)
+	}
+	return 0
+}
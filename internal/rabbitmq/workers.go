@@ -0,0 +1,72 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// workerPool hands deliveries to a fixed set of goroutines. Without an order
+// key all workers share one queue; with one, every key is pinned to a single
+// worker so messages with the same key are handled in arrival order.
+type workerPool struct {
+	queues   []chan *Delivery
+	orderKey []string
+	wg       sync.WaitGroup
+}
+
+func newWorkerPool(workers int, orderKey []string, handler MessageHandler) *workerPool {
+	queueCount := 1
+	if len(orderKey) > 0 {
+		queueCount = workers
+	}
+
+	pool := &workerPool{
+		queues:   make([]chan *Delivery, queueCount),
+		orderKey: orderKey,
+	}
+	for i := range pool.queues {
+		pool.queues[i] = make(chan *Delivery)
+	}
+
+	pool.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		queue := pool.queues[i%queueCount]
+		go func() {
+			defer pool.wg.Done()
+			for delivery := range queue {
+				handler(delivery)
+			}
+		}()
+	}
+
+	return pool
+}
+
+func (p *workerPool) dispatch(delivery *Delivery) {
+	p.queues[p.queueIndex(delivery.Message)] <- delivery
+}
+
+func (p *workerPool) queueIndex(message map[string]interface{}) int {
+	if len(p.queues) == 1 {
+		return 0
+	}
+
+	parts := make([]string, len(p.orderKey))
+	for i, field := range p.orderKey {
+		parts[i] = fmt.Sprint(message[field])
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.Join(parts, "\x00")))
+	return int(hash.Sum32() % uint32(len(p.queues)))
+}
+
+// stop waits for the workers to finish the deliveries they already hold.
+func (p *workerPool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
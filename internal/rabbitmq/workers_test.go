@@ -0,0 +1,86 @@
+package rabbitmq
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWorkerPoolOrdering tests that messages with the same order key are
+// handled one at a time in arrival order.
+func TestWorkerPoolOrdering(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[interface{}][]int)
+	running := make(map[interface{}]bool)
+	overlapped := false
+
+	pool := newWorkerPool(4, []string{"task_id"}, func(delivery *Delivery) {
+		key := delivery.Message["task_id"]
+		mu.Lock()
+		overlapped = overlapped || running[key]
+		running[key] = true
+		mu.Unlock()
+
+		time.Sleep(100 * time.Microsecond)
+
+		mu.Lock()
+		running[key] = false
+		handled[key] = append(handled[key], delivery.Message["seq"].(int))
+		mu.Unlock()
+	})
+
+	for seq := 0; seq < 100; seq++ {
+		pool.dispatch(&Delivery{Message: map[string]interface{}{"task_id": float64(seq % 3), "seq": seq}})
+	}
+	pool.stop()
+
+	assert.False(t, overlapped, "Messages with the same key should not be handled concurrently")
+	for key, seqs := range handled {
+		for i := 1; i < len(seqs); i++ {
+			assert.Less(t, seqs[i-1], seqs[i], "Messages of task %v should be handled in order", key)
+		}
+	}
+	assert.Len(t, handled, 3)
+}
+
+// TestWorkerPoolParallelism tests that messages with different order keys are
+// handled in parallel.
+func TestWorkerPoolParallelism(t *testing.T) {
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	release := make(chan struct{})
+
+	pool := newWorkerPool(4, []string{"task_id"}, func(delivery *Delivery) {
+		arrived.Done()
+		<-release
+	})
+
+	first := map[string]interface{}{"task_id": float64(1)}
+	second := map[string]interface{}{"task_id": float64(2)}
+	for pool.queueIndex(second) == pool.queueIndex(first) {
+		second["task_id"] = second["task_id"].(float64) + 1
+	}
+
+	pool.dispatch(&Delivery{Message: first})
+	pool.dispatch(&Delivery{Message: second})
+
+	both := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(both)
+	}()
+	select {
+	case <-both:
+	case <-time.After(time.Second):
+		t.Fatal("Messages with different keys should be handled at the same time")
+	}
+	close(release)
+	pool.stop()
+
+	unordered := newWorkerPool(2, nil, func(*Delivery) {})
+	require.Len(t, unordered.queues, 1, "Without an order key all workers should share a queue")
+	unordered.stop()
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"strconv"
 	"time"
@@ -25,6 +26,16 @@ func NewRedisClient() *RedisClient {
 	}
 }
 
+func NewRedisClientWithOptions(address, password string, db int) *RedisClient {
+	return &RedisClient{
+		client: redis.NewClient(&redis.Options{
+			Addr:     address,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
 func (r *RedisClient) SetData(cacheKey string, data interface{}, timeExpired time.Duration) error {
 	jsonValue, err := json.Marshal(data)
 	if err != nil {
@@ -57,6 +68,37 @@ func (r *RedisClient) GetData(cacheKey string, v interface{}) error {
 	return nil
 }
 
+// GetBytes returns the raw value stored at cacheKey; a missing key yields
+// IsMiss(err) == true.
+func (r *RedisClient) GetBytes(ctx context.Context, cacheKey string) ([]byte, error) {
+	return r.client.Get(ctx, cacheKey).Bytes()
+}
+
+func (r *RedisClient) SetBytes(ctx context.Context, cacheKey string, value []byte, timeExpired time.Duration) error {
+	return r.client.Set(ctx, cacheKey, value, timeExpired).Err()
+}
+
+// GetInt returns the integer stored at key, or 0 when the key does not exist.
+func (r *RedisClient) GetInt(ctx context.Context, key string) (int64, error) {
+	value, err := r.client.Get(ctx, key).Int64()
+	if IsMiss(err) {
+		return 0, nil
+	}
+	return value, err
+}
+
+func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
+func (r *RedisClient) Close() error {
+	return r.client.Close()
+}
+
+func IsMiss(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	value, err := strconv.Atoi(util.GetEnv(key, strconv.Itoa(defaultValue)))
 	if err != nil {
@@ -0,0 +1,161 @@
+// Package retention deletes logs once they are older than the retention of
+// their organization or project.
+//
+// The logs collection carries a TTL index on timestamp set to the longest
+// retention in effect, so nothing outlives every policy even when no enforcer
+// runs. Shorter retentions, which one TTL index cannot express per tenant, are
+// applied by the Enforcer.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+)
+
+// Scope selects the logs a policy applies to. A zero ProjectID covers the
+// whole organization; the zero Scope covers everything.
+type Scope struct {
+	Organization string
+	ProjectID    int64
+}
+
+// Contains reports whether other is the same as, or narrower than, s.
+func (s Scope) Contains(other Scope) bool {
+	if s.Organization == "" {
+		return true
+	}
+	if s.Organization != other.Organization {
+		return false
+	}
+	return s.ProjectID == 0 || s.ProjectID == other.ProjectID
+}
+
+func (s Scope) String() string {
+	switch {
+	case s.Organization == "":
+		return "default"
+	case s.ProjectID == 0:
+		return s.Organization
+	default:
+		return fmt.Sprintf("%s/%d", s.Organization, s.ProjectID)
+	}
+}
+
+// Policy overrides the default retention for a scope.
+type Policy struct {
+	Scope     Scope
+	Retention time.Duration
+}
+
+// Backend is the storage the enforcer works on.
+type Backend interface {
+	Policies(ctx context.Context) ([]Policy, error)
+	// DeleteBefore deletes the logs in scope, outside every excluded scope,
+	// that were logged before cutoff.
+	DeleteBefore(ctx context.Context, scope Scope, exclude []Scope, cutoff time.Time) (int64, error)
+	// SetTTL sets the expiry of the TTL index.
+	SetTTL(ctx context.Context, ttl time.Duration) error
+}
+
+// DeletedListener is told about every scope logs were deleted from; a zero
+// ProjectID or Organization stands for all of them. It runs on the enforcing
+// goroutine.
+type DeletedListener func(organization string, projectID int64)
+
+type Enforcer struct {
+	backend   Backend
+	retain    time.Duration
+	interval  time.Duration
+	labels    metrics.Labels
+	now       func() time.Time
+	ttl       time.Duration
+	listeners []DeletedListener
+}
+
+func NewEnforcer(backend Backend, defaultRetention, interval time.Duration, labels metrics.Labels) *Enforcer {
+	return &Enforcer{
+		backend:  backend,
+		retain:   defaultRetention,
+		interval: interval,
+		labels:   labels,
+		now:      time.Now,
+	}
+}
+
+// OnDeleted registers listener; it must be called before Run.
+func (e *Enforcer) OnDeleted(listener DeletedListener) {
+	e.listeners = append(e.listeners, listener)
+}
+
+// Run enforces retention right away and then every interval until ctx is done.
+func (e *Enforcer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Enforce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to enforce retention: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce deletes every log that is past the retention of its scope. Each
+// scope excludes the narrower scopes that have a policy of their own.
+func (e *Enforcer) Enforce(ctx context.Context) error {
+	policies, err := e.backend.Policies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load retention policies: %v", err)
+	}
+
+	longest := e.retain
+	for _, policy := range policies {
+		if policy.Retention > longest {
+			longest = policy.Retention
+		}
+	}
+	if longest != e.ttl {
+		if err := e.backend.SetTTL(ctx, longest); err != nil {
+			return fmt.Errorf("failed to set retention TTL: %v", err)
+		}
+		log.Printf("Retention TTL index set to %s", longest)
+		e.ttl = longest
+	}
+
+	all := append([]Policy{{Retention: e.retain}}, policies...)
+	now := e.now()
+
+	var failed error
+	for _, policy := range all {
+		var exclude []Scope
+		for _, other := range all {
+			if other.Scope != policy.Scope && policy.Scope.Contains(other.Scope) {
+				exclude = append(exclude, other.Scope)
+			}
+		}
+
+		deleted, err := e.backend.DeleteBefore(ctx, policy.Scope, exclude, now.Add(-policy.Retention))
+		if err != nil {
+			log.Printf("Failed to enforce retention for %s: %v", policy.Scope, err)
+			failed = err
+			continue
+		}
+		if deleted > 0 {
+			metrics.RetentionDeleted.WithLabelValues(e.labels.Organization(policy.Scope.Organization)).Add(float64(deleted))
+			log.Printf("Deleted %d logs of %s older than %s", deleted, policy.Scope, policy.Retention)
+			for _, listener := range e.listeners {
+				listener(policy.Scope.Organization, policy.Scope.ProjectID)
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,76 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type deletion struct {
+	scope   Scope
+	exclude []Scope
+	cutoff  time.Time
+}
+
+type backend struct {
+	policies  []Policy
+	deletions []deletion
+	ttl       time.Duration
+}
+
+func (b *backend) Policies(ctx context.Context) ([]Policy, error) {
+	return b.policies, nil
+}
+
+func (b *backend) DeleteBefore(ctx context.Context, scope Scope, exclude []Scope, cutoff time.Time) (int64, error) {
+	b.deletions = append(b.deletions, deletion{scope, exclude, cutoff})
+	return 1, nil
+}
+
+func (b *backend) SetTTL(ctx context.Context, ttl time.Duration) error {
+	b.ttl = ttl
+	return nil
+}
+
+// TestEnforce tests that every scope is enforced with its own retention and
+// without touching narrower scopes that have their own policy.
+func TestEnforce(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	acme := Scope{Organization: "acme"}
+	acme7 := Scope{Organization: "acme", ProjectID: 7}
+	globex := Scope{Organization: "globex"}
+
+	b := &backend{policies: []Policy{
+		{Scope: acme, Retention: 30 * day},
+		{Scope: acme7, Retention: 365 * day},
+		{Scope: globex, Retention: 7 * day},
+	}}
+	enforcer := NewEnforcer(b, 90*day, time.Hour, metrics.Labels{})
+	enforcer.now = func() time.Time { return now }
+	var deleted []Scope
+	enforcer.OnDeleted(func(organization string, projectID int64) {
+		deleted = append(deleted, Scope{Organization: organization, ProjectID: projectID})
+	})
+
+	assert.NoError(t, enforcer.Enforce(context.Background()))
+	assert.Equal(t, 365*day, b.ttl, "The TTL index should keep logs as long as the longest policy")
+	assert.Equal(t, []deletion{
+		{Scope{}, []Scope{acme, acme7, globex}, now.Add(-90 * day)},
+		{acme, []Scope{acme7}, now.Add(-30 * day)},
+		{acme7, nil, now.Add(-365 * day)},
+		{globex, nil, now.Add(-7 * day)},
+	}, b.deletions)
+	assert.Equal(t, []Scope{{}, acme, acme7, globex}, deleted, "Listeners should be told about every scope logs were deleted from")
+}
+
+// TestScopeContains tests which scopes are narrower than others.
+func TestScopeContains(t *testing.T) {
+	assert.True(t, Scope{}.Contains(Scope{Organization: "acme", ProjectID: 7}))
+	assert.True(t, Scope{Organization: "acme"}.Contains(Scope{Organization: "acme", ProjectID: 7}))
+	assert.False(t, Scope{Organization: "acme", ProjectID: 7}.Contains(Scope{Organization: "acme"}))
+	assert.False(t, Scope{Organization: "acme"}.Contains(Scope{Organization: "globex"}))
+}
@@ -0,0 +1,235 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+)
+
+const recordTimeout = 5 * time.Second
+
+// Recorder persists missed runs. Every logger instance checks the same stored
+// state, so each records the same run; doing so twice must not be an error.
+type Recorder interface {
+	RecordMissedRun(ctx context.Context, run model.MissedRun) error
+}
+
+// Detector tracks when every enabled task was last seen and reports the runs
+// that did not happen. A task counts as seen whenever a snapshot of it is
+// stored or one of its executions starts. Both are read back from the stores
+// on every check, so that instances sharing the ingest queue agree, and only
+// tasks with a snapshot within the lookback are tracked.
+type Detector struct {
+	logs       store.LogStore
+	executions store.ExecutionStore
+	recorder   Recorder
+	grace      time.Duration
+	interval   time.Duration
+	lookback   time.Duration
+	labels     metrics.Labels
+	now        func() time.Time
+
+	mu    sync.Mutex
+	tasks map[taskKey]*tracked
+}
+
+type taskKey struct {
+	organization string
+	projectID    int
+	taskID       int
+}
+
+type tracked struct {
+	task     model.Task
+	lastSeen time.Time
+	next     time.Time
+	// reported is the expected run a missed run was last recorded for.
+	reported time.Time
+}
+
+// Overdue is a task whose expected run is more than the grace period ago.
+type Overdue struct {
+	Task       model.Task
+	LastSeen   time.Time
+	ExpectedAt time.Time
+}
+
+func NewDetector(logs store.LogStore, executions store.ExecutionStore, recorder Recorder, grace, interval, lookback time.Duration, labels metrics.Labels) *Detector {
+	return &Detector{
+		logs:       logs,
+		executions: executions,
+		recorder:   recorder,
+		grace:      grace,
+		interval:   interval,
+		lookback:   lookback,
+		labels:     labels,
+		now:        time.Now,
+		tasks:      make(map[taskKey]*tracked),
+	}
+}
+
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.check(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.check(ctx)
+		}
+	}
+}
+
+// Overdue lists the overdue tasks of an organization and project, the most
+// overdue first.
+func (d *Detector) Overdue(organization string, projectID int64) []Overdue {
+	deadline := d.now().Add(-d.grace)
+
+	d.mu.Lock()
+	var overdue []Overdue
+	for key, t := range d.tasks {
+		if key.organization != organization || int64(key.projectID) != projectID {
+			continue
+		}
+		if !t.next.IsZero() && t.next.Before(deadline) {
+			overdue = append(overdue, Overdue{Task: t.task, LastSeen: t.lastSeen, ExpectedAt: t.next})
+		}
+	}
+	d.mu.Unlock()
+
+	sort.Slice(overdue, func(i, j int) bool {
+		return overdue[i].ExpectedAt.Before(overdue[j].ExpectedAt)
+	})
+	return overdue
+}
+
+// refresh replaces the tracked tasks with the latest snapshots and executions
+// in the stores, keeping what was already reported.
+func (d *Detector) refresh(ctx context.Context) error {
+	since := d.now().Add(-d.lookback)
+
+	entries, err := d.logs.Latest(ctx, query.Filter{From: since})
+	if err != nil {
+		return fmt.Errorf("failed to read latest tasks: %v", err)
+	}
+	executions, err := d.executions.LatestExecutions(ctx, query.ExecutionFilter{From: since})
+	if err != nil {
+		return fmt.Errorf("failed to read latest executions: %v", err)
+	}
+
+	tasks := make(map[taskKey]*tracked, len(entries))
+	for _, entry := range entries {
+		if !entry.Task.Disabled {
+			tasks[keyOf(entry.Task)] = &tracked{task: entry.Task, lastSeen: entry.Task.TimeStamp}
+		}
+	}
+	for _, entry := range executions {
+		execution := entry.Execution
+		key := taskKey{execution.Organization, execution.ProjectID, execution.TaskID}
+		if t, ok := tasks[key]; ok && execution.StartedAt.After(t.lastSeen) {
+			t.lastSeen = execution.StartedAt
+		}
+	}
+	for _, t := range tasks {
+		t.next = d.nextRun(t.task, t.lastSeen)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, t := range tasks {
+		if previous, ok := d.tasks[key]; ok {
+			t.reported = previous.reported
+		}
+	}
+	d.tasks = tasks
+	return nil
+}
+
+func (d *Detector) nextRun(task model.Task, after time.Time) time.Time {
+	next, err := NextRun(task, after)
+	if err != nil {
+		log.Printf("Failed to compute next run of task %d: %v", task.ID, err)
+	}
+	return next
+}
+
+// check records a missed run for every task that became overdue since the
+// last check.
+func (d *Detector) check(ctx context.Context) {
+	if err := d.refresh(ctx); err != nil {
+		log.Printf("Failed to refresh the missed-run detector: %v", err)
+		return
+	}
+
+	now := d.now()
+	deadline := now.Add(-d.grace)
+
+	var missed []model.MissedRun
+	overdue := 0
+
+	d.mu.Lock()
+	for _, t := range d.tasks {
+		if t.next.IsZero() || !t.next.Before(deadline) {
+			continue
+		}
+		overdue++
+		if t.reported.Equal(t.next) {
+			continue
+		}
+		t.reported = t.next
+		missed = append(missed, model.MissedRun{
+			TaskID:       t.task.ID,
+			Organization: t.task.Organization,
+			ProjectID:    t.task.ProjectID,
+			Type:         t.task.Type,
+			Name:         t.task.Name,
+			LastSeen:     t.lastSeen,
+			ExpectedAt:   t.next,
+			DetectedAt:   now,
+		})
+	}
+	d.mu.Unlock()
+
+	metrics.OverdueTasks.Set(float64(overdue))
+
+	for _, run := range missed {
+		recordCtx, cancel := context.WithTimeout(ctx, recordTimeout)
+		err := d.recorder.RecordMissedRun(recordCtx, run)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to record missed run: %v", err)
+			d.unreport(run)
+			continue
+		}
+
+		metrics.MissedRuns.WithLabelValues(d.labels.Organization(run.Organization)).Inc()
+		log.Printf("Task %d of %s/%d missed its run expected at %s", run.TaskID, run.Organization, run.ProjectID, run.ExpectedAt.Format(time.RFC3339))
+	}
+}
+
+// unreport lets the next check retry a missed run that could not be recorded.
+func (d *Detector) unreport(run model.MissedRun) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := taskKey{run.Organization, run.ProjectID, run.TaskID}
+	if t, ok := d.tasks[key]; ok && t.reported.Equal(run.ExpectedAt) {
+		t.reported = time.Time{}
+	}
+}
+
+func keyOf(task model.Task) taskKey {
+	return taskKey{task.Organization, task.ProjectID, task.ID}
+}
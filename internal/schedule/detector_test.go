@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/metrics"
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/store/memory"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	runs []model.MissedRun
+}
+
+func (r *recorder) RecordMissedRun(ctx context.Context, run model.MissedRun) error {
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func document(id int, taskType pb.TaskType, interval int64, cronExpr []interface{}, disabled bool, timestamp time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"task_id":              float64(id),
+		"organization":         "acme",
+		"project_id":           float64(7),
+		"type":                 float64(taskType),
+		"interval":             float64(interval),
+		"task_cron_expression": cronExpr,
+		"disabled":             disabled,
+		"timestamp":            timestamp,
+	}
+}
+
+// TestNextRun tests the expected next run of CRON and INTERVAL tasks.
+func TestNextRun(t *testing.T) {
+	after := time.Date(2024, 1, 3, 2, 10, 0, 0, time.UTC)
+
+	next, err := NextRun(model.Task{Type: pb.TaskType_CRON, CronExpr: []string{"0 3 * * *", "30 2 * * *"}}, after)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 3, 2, 30, 0, 0, time.UTC), next, "The earliest expression should win")
+
+	next, err = NextRun(model.Task{Type: pb.TaskType_INTERVAL, Interval: 60}, after)
+	assert.NoError(t, err)
+	assert.Equal(t, after.Add(time.Minute), next, "Interval tasks should run every Interval seconds")
+
+	_, err = NextRun(model.Task{Type: pb.TaskType_CRON, CronExpr: []string{"not cron"}}, after)
+	assert.Error(t, err, "An invalid expression should be reported")
+}
+
+// TestDetector tests that overdue tasks are listed and their missed runs
+// recorded once, from state read back from the stores.
+func TestDetector(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)
+	now := start
+	logs := memory.NewLogStore()
+	executions := memory.NewExecutionStore()
+	runs := &recorder{}
+	newDetector := func() *Detector {
+		detector := NewDetector(logs, executions, runs, time.Minute, time.Second, time.Hour, metrics.Labels{})
+		detector.now = func() time.Time { return now }
+		return detector
+	}
+	detector, replica := newDetector(), newDetector()
+
+	require.NoError(t, logs.InsertMany(ctx, []map[string]interface{}{
+		document(1, pb.TaskType_INTERVAL, 300, []interface{}{}, false, start),
+		document(2, pb.TaskType_CRON, 0, []interface{}{"*/10 * * * *"}, false, start),
+		document(3, pb.TaskType_INTERVAL, 60, []interface{}{}, true, start),
+		document(4, pb.TaskType_INTERVAL, 60, []interface{}{}, false, start.Add(-time.Minute)),
+	}))
+	require.NoError(t, logs.Insert(ctx, document(4, pb.TaskType_INTERVAL, 60, []interface{}{}, true, start)))
+
+	now = start.Add(5 * time.Minute)
+	detector.check(ctx)
+	assert.Empty(t, detector.Overdue("acme", 7), "No task should be overdue within the grace period")
+
+	now = start.Add(7 * time.Minute)
+	detector.check(ctx)
+	detector.check(ctx)
+	overdue := detector.Overdue("acme", 7)
+	assert.Len(t, overdue, 1, "Only the interval task should be overdue, a later disabled snapshot winning")
+	assert.Equal(t, 1, overdue[0].Task.ID)
+	assert.Equal(t, start.Add(5*time.Minute), overdue[0].ExpectedAt)
+	assert.Len(t, runs.runs, 1, "A missed run should be recorded once")
+
+	replica.check(ctx)
+	assert.Equal(t, overdue, replica.Overdue("acme", 7), "Instances reading the same stores should agree")
+
+	require.NoError(t, executions.InsertMany(ctx, []map[string]interface{}{{
+		"task_id":      float64(1),
+		"organization": "acme",
+		"project_id":   float64(7),
+		"outcome":      float64(pb.ExecutionOutcome_OUTCOME_SUCCESS),
+		"started_at":   now,
+	}}))
+	detector.check(ctx)
+	assert.Empty(t, detector.Overdue("acme", 7), "An execution should count as a run")
+}
+
+// TestDetectorForgetsStaleTasks tests that a task that stops reporting is
+// tracked only until its last snapshot is older than the lookback.
+func TestDetectorForgetsStaleTasks(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)
+	now := start.Add(30 * time.Minute)
+	logs := memory.NewLogStore()
+	detector := NewDetector(logs, memory.NewExecutionStore(), &recorder{}, time.Minute, time.Second, time.Hour, metrics.Labels{})
+	detector.now = func() time.Time { return now }
+
+	require.NoError(t, logs.Insert(ctx, document(1, pb.TaskType_INTERVAL, 60, []interface{}{}, false, start)))
+
+	detector.check(ctx)
+	assert.Len(t, detector.Overdue("acme", 7), 1, "A task that stopped reporting should be overdue")
+
+	now = start.Add(2 * time.Hour)
+	detector.check(ctx)
+	assert.Empty(t, detector.Overdue("acme", 7), "A task should be forgotten once its last snapshot leaves the lookback")
+	assert.Empty(t, detector.tasks, "A forgotten task should not be kept in memory")
+}
@@ -0,0 +1,38 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/robfig/cron/v3"
+)
+
+// NextRun returns when task is next due after after. CRON tasks use the
+// earliest of their expressions, INTERVAL tasks run every Interval seconds. A
+// zero time means the task has no schedule to check.
+func NextRun(task model.Task, after time.Time) (time.Time, error) {
+	switch task.Type {
+	case pb.TaskType_CRON:
+		var next time.Time
+		for _, expression := range task.CronExpr {
+			schedule, err := cron.ParseStandard(expression)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid cron expression %q: %v", expression, err)
+			}
+			candidate := schedule.Next(after)
+			if next.IsZero() || candidate.Before(next) {
+				next = candidate
+			}
+		}
+		return next, nil
+	case pb.TaskType_INTERVAL:
+		if task.Interval <= 0 {
+			return time.Time{}, nil
+		}
+		return after.Add(time.Duration(task.Interval) * time.Second), nil
+	default:
+		return time.Time{}, nil
+	}
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+)
+
+// ExecutionStore persists execution events. Results are ordered by start time,
+// newest first, and then by id.
+type ExecutionStore interface {
+	// InsertMany behaves like LogStore.InsertMany.
+	InsertMany(ctx context.Context, documents []map[string]interface{}) error
+	FindExecutions(ctx context.Context, q ExecutionQuery) ([]ExecutionEntry, error)
+	// LatestExecutions is LogStore.Latest for executions, by start time.
+	LatestExecutions(ctx context.Context, filter query.ExecutionFilter) ([]ExecutionEntry, error)
+}
+
+type ExecutionEntry struct {
+	ID        string
+	Execution model.Execution
+}
+
+func (e ExecutionEntry) Cursor() Cursor {
+	return Cursor{TimeStamp: e.Execution.StartedAt, ID: e.ID}
+}
+
+type ExecutionQuery struct {
+	Filter query.ExecutionFilter
+	After  *Cursor
+	// Limit caps the number of entries returned; zero means no cap.
+	Limit int64
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExecutionStore keeps executions in memory, sorted the way the Mongo store
+// returns them.
+type ExecutionStore struct {
+	mu      sync.RWMutex
+	entries []store.ExecutionEntry
+}
+
+func NewExecutionStore() *ExecutionStore {
+	return &ExecutionStore{}
+}
+
+func (s *ExecutionStore) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := make(map[int]error)
+	for i, document := range documents {
+		execution, err := model.ExecutionFromDocument(document)
+		if err != nil {
+			failures[i] = fmt.Errorf("%w: %v", store.ErrRejected, err)
+			continue
+		}
+
+		entry := store.ExecutionEntry{ID: primitive.NewObjectID().Hex(), Execution: execution}
+		if id, ok := document["_id"].(primitive.ObjectID); ok {
+			entry.ID = id.Hex()
+		}
+		s.insert(entry)
+	}
+
+	if len(failures) > 0 {
+		return &store.InsertError{Failures: failures}
+	}
+	return nil
+}
+
+func (s *ExecutionStore) FindExecutions(ctx context.Context, q store.ExecutionQuery) ([]store.ExecutionEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []store.ExecutionEntry
+	for _, entry := range s.entries {
+		if q.Limit > 0 && int64(len(matches)) >= q.Limit {
+			break
+		}
+		if q.After != nil && !store.Before(*q.After, entry.Cursor()) {
+			continue
+		}
+		if q.Filter.Match(entry.Execution) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
+func (s *ExecutionStore) LatestExecutions(ctx context.Context, filter query.ExecutionFilter) ([]store.ExecutionEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[latestKey]struct{})
+	var latest []store.ExecutionEntry
+	for _, entry := range s.entries {
+		key := latestKey{entry.Execution.Organization, entry.Execution.ProjectID, entry.Execution.TaskID}
+		if _, ok := seen[key]; ok || !filter.Match(entry.Execution) {
+			continue
+		}
+		seen[key] = struct{}{}
+		latest = append(latest, entry)
+	}
+	return latest, nil
+}
+
+func (s *ExecutionStore) insert(entry store.ExecutionEntry) {
+	cursor := entry.Cursor()
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return store.Before(cursor, s.entries[i].Cursor())
+	})
+
+	s.entries = append(s.entries, store.ExecutionEntry{})
+	copy(s.entries[i+1:], s.entries[i:])
+	s.entries[i] = entry
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LogStore keeps logs in memory, sorted the way the Mongo store returns them.
+// It is meant for tests and local development.
+type LogStore struct {
+	mu      sync.RWMutex
+	entries []store.Entry
+}
+
+func NewLogStore() *LogStore {
+	return &LogStore{}
+}
+
+func (s *LogStore) Insert(ctx context.Context, document map[string]interface{}) error {
+	entry, err := newEntry(document)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.insert(entry)
+	return nil
+}
+
+func (s *LogStore) InsertMany(ctx context.Context, documents []map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := make(map[int]error)
+	for i, document := range documents {
+		entry, err := newEntry(document)
+		if err != nil {
+			failures[i] = err
+			continue
+		}
+		s.insert(entry)
+	}
+
+	if len(failures) > 0 {
+		return &store.InsertError{Failures: failures}
+	}
+	return nil
+}
+
+func (s *LogStore) Find(ctx context.Context, q store.Query) ([]store.Entry, error) {
+	var entries []store.Entry
+	err := s.Each(ctx, q, func(entry store.Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (s *LogStore) Each(ctx context.Context, q store.Query, fn func(store.Entry) error) error {
+	s.mu.RLock()
+	matches := s.match(q)
+	s.mu.RUnlock()
+
+	for _, entry := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LogStore) Count(ctx context.Context, filter query.Filter) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.match(store.Query{Filter: filter}))), nil
+}
+
+func (s *LogStore) Latest(ctx context.Context, filter query.Filter) ([]store.Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[latestKey]struct{})
+	var latest []store.Entry
+	for _, entry := range s.entries {
+		key := latestKey{entry.Task.Organization, entry.Task.ProjectID, entry.Task.ID}
+		if _, ok := seen[key]; ok || !filter.Match(entry.Task) {
+			continue
+		}
+		seen[key] = struct{}{}
+		latest = append(latest, entry)
+	}
+	return latest, nil
+}
+
+func (s *LogStore) Delete(ctx context.Context, filter query.Filter) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.entries[:0]
+	for _, entry := range s.entries {
+		if !filter.Match(entry.Task) {
+			kept = append(kept, entry)
+		}
+	}
+
+	deleted := len(s.entries) - len(kept)
+	s.entries = kept
+	return int64(deleted), nil
+}
+
+func (s *LogStore) insert(entry store.Entry) {
+	cursor := entry.Cursor()
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return store.Before(cursor, s.entries[i].Cursor())
+	})
+
+	s.entries = append(s.entries, store.Entry{})
+	copy(s.entries[i+1:], s.entries[i:])
+	s.entries[i] = entry
+}
+
+// match returns a copy of the matching entries so that callers can iterate
+// without holding the lock.
+func (s *LogStore) match(q store.Query) []store.Entry {
+	var matches []store.Entry
+	for _, entry := range s.entries {
+		if q.Limit > 0 && int64(len(matches)) >= q.Limit {
+			break
+		}
+		if q.After != nil && !store.Before(*q.After, entry.Cursor()) {
+			continue
+		}
+		if q.Filter.Match(entry.Task) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// latestKey identifies a task across both stores.
+type latestKey struct {
+	organization string
+	projectID    int
+	taskID       int
+}
+
+func newEntry(document map[string]interface{}) (store.Entry, error) {
+	entry, err := store.EntryFromDocument(document)
+	if err != nil {
+		return store.Entry{}, fmt.Errorf("%w: %v", store.ErrRejected, err)
+	}
+	if entry.ID == "" {
+		entry.ID = primitive.NewObjectID().Hex()
+	}
+	return entry, nil
+}
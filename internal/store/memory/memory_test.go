@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/bondzai/logger/internal/store"
+	"github.com/bondzai/logger/internal/store/storetest"
+)
+
+func TestLogStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.LogStore {
+		return NewLogStore()
+	})
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrRejected wraps failures caused by the document itself, such as a
+// duplicate key, which will fail the same way however often they are retried.
+var ErrRejected = errors.New("document rejected")
+
+// LogStore persists task logs. Results are always ordered newest first, by
+// timestamp and then by id, so that a Cursor identifies a unique position.
+type LogStore interface {
+	Insert(ctx context.Context, document map[string]interface{}) error
+	// InsertMany writes documents independently of each other. When only some
+	// of them fail it returns an *InsertError describing which.
+	InsertMany(ctx context.Context, documents []map[string]interface{}) error
+	Find(ctx context.Context, q Query) ([]Entry, error)
+	// Each is Find without buffering: fn is called for every entry in order
+	// and iteration stops at the first error it returns.
+	Each(ctx context.Context, q Query, fn func(Entry) error) error
+	Count(ctx context.Context, filter query.Filter) (int64, error)
+	// Latest returns the newest matching entry of every task, one per
+	// organization, project and task id, in no particular order.
+	Latest(ctx context.Context, filter query.Filter) ([]Entry, error)
+	Delete(ctx context.Context, filter query.Filter) (int64, error)
+}
+
+type Entry struct {
+	ID   string
+	Task model.Task
+}
+
+// EntryFromDocument decodes a document as it was handed to the store. The id
+// is empty when the document carries no ObjectID _id.
+func EntryFromDocument(document map[string]interface{}) (Entry, error) {
+	task, err := model.TaskFromDocument(document)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var id string
+	if objectID, ok := document["_id"].(primitive.ObjectID); ok {
+		id = objectID.Hex()
+	}
+	return Entry{ID: id, Task: task}, nil
+}
+
+func (e Entry) Cursor() Cursor {
+	return Cursor{TimeStamp: e.Task.TimeStamp, ID: e.ID}
+}
+
+// Cursor is the sort key of an entry. A query with After set starts strictly
+// after that entry.
+type Cursor struct {
+	TimeStamp time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+type Query struct {
+	Filter query.Filter
+	After  *Cursor
+	// Limit caps the number of entries returned; zero means no cap.
+	Limit int64
+}
+
+// Before reports whether an entry with cursor a sorts ahead of one with b.
+func Before(a, b Cursor) bool {
+	if !a.TimeStamp.Equal(b.TimeStamp) {
+		return a.TimeStamp.After(b.TimeStamp)
+	}
+	return a.ID > b.ID
+}
+
+type InsertError struct {
+	// Failures maps the index of every document that was not written to the
+	// reason; all other documents were written.
+	Failures map[int]error
+}
+
+func (e *InsertError) Error() string {
+	return fmt.Sprintf("failed to insert %d documents", len(e.Failures))
+}
@@ -0,0 +1,196 @@
+// Package storetest is the conformance suite every store.LogStore
+// implementation must pass, so that backends stay interchangeable.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	pb "github.com/bondzai/logger/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run exercises a store. newStore must return an empty store on every call.
+func Run(t *testing.T, newStore func(t *testing.T) store.LogStore) {
+	t.Run("SortsNewestFirst", func(t *testing.T) { testSort(t, newStore(t)) })
+	t.Run("Filters", func(t *testing.T) { testFilters(t, newStore(t)) })
+	t.Run("Pages", func(t *testing.T) { testPages(t, newStore(t)) })
+	t.Run("Each", func(t *testing.T) { testEach(t, newStore(t)) })
+	t.Run("Latest", func(t *testing.T) { testLatest(t, newStore(t)) })
+	t.Run("CountAndDelete", func(t *testing.T) { testCountAndDelete(t, newStore(t)) })
+}
+
+// task builds a document the way ingestion hands it to the store: decoded
+// from JSON, so numbers are float64, and then normalized.
+func task(id int, organization string, project int, taskType pb.TaskType, name string, disabled bool, timestamp string) map[string]interface{} {
+	document := map[string]interface{}{
+		"task_id":              float64(id),
+		"organization":         organization,
+		"project_id":           float64(project),
+		"type":                 float64(taskType),
+		"task_name":            name,
+		"interval":             float64(60),
+		"task_cron_expression": []interface{}{},
+		"disabled":             disabled,
+		"timestamp":            timestamp,
+	}
+	model.NormalizeTask(document, time.Now())
+	return document
+}
+
+func seed(t *testing.T, s store.LogStore) {
+	documents := []map[string]interface{}{
+		task(1, "acme", 7, pb.TaskType_CRON, "nightly-backup", false, "2024-01-03T02:15:00Z"),
+		task(2, "acme", 7, pb.TaskType_INTERVAL, "heartbeat", false, "2024-01-03T02:30:00Z"),
+		task(3, "acme", 7, pb.TaskType_CRON, "Backup-verify", true, "2024-01-03T03:45:00Z"),
+		task(4, "acme", 7, pb.TaskType_CRON, "report", false, "2024-01-03T05:00:00Z"),
+		task(5, "acme", 8, pb.TaskType_CRON, "backup", false, "2024-01-03T02:45:00Z"),
+		task(6, "globex", 7, pb.TaskType_CRON, "backup", false, "2024-01-03T02:50:00Z"),
+	}
+	require.NoError(t, s.InsertMany(context.Background(), documents), "InsertMany should not return an error")
+}
+
+func ids(entries []store.Entry) []int {
+	result := make([]int, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.Task.ID
+	}
+	return result
+}
+
+func testSort(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+	require.NoError(t, s.Insert(ctx, task(7, "acme", 7, pb.TaskType_CRON, "report", false, "2024-01-03T05:00:00Z")))
+
+	entries, err := s.Find(ctx, store.Query{Filter: query.Filter{Organization: "acme", ProjectID: 7}})
+	require.NoError(t, err, "Find should not return an error")
+	assert.Equal(t, []int{7, 4, 3, 2, 1}, ids(entries), "Entries should be newest first, later inserts first on ties")
+
+	entries, err = s.Find(ctx, store.Query{Filter: query.Filter{Organization: "acme"}, Limit: 2})
+	require.NoError(t, err, "Find should not return an error")
+	assert.Equal(t, []int{7, 4}, ids(entries), "Limit should cap the result")
+}
+
+func testFilters(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+
+	disabled := true
+	enabled := false
+	base := query.Filter{Organization: "acme", ProjectID: 7}
+	cases := map[string]struct {
+		modify func(f *query.Filter)
+		want   []int
+	}{
+		"task ids":      {func(f *query.Filter) { f.TaskIDs = []int64{1, 4} }, []int{4, 1}},
+		"types":         {func(f *query.Filter) { f.Types = []pb.TaskType{pb.TaskType_INTERVAL} }, []int{2}},
+		"name prefix":   {func(f *query.Filter) { f.NamePrefix = "Backup" }, []int{3}},
+		"name contains": {func(f *query.Filter) { f.NameContains = "BACKUP" }, []int{3, 1}},
+		"disabled":      {func(f *query.Filter) { f.Disabled = &disabled }, []int{3}},
+		"enabled":       {func(f *query.Filter) { f.Disabled = &enabled }, []int{4, 2, 1}},
+		"time range": {func(f *query.Filter) {
+			f.From = time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)
+			f.To = time.Date(2024, 1, 3, 3, 45, 0, 0, time.UTC)
+		}, []int{2, 1}},
+		"combined": {func(f *query.Filter) {
+			f.Types = []pb.TaskType{pb.TaskType_CRON}
+			f.NameContains = "backup"
+			f.From = time.Date(2024, 1, 3, 2, 0, 0, 0, time.UTC)
+			f.To = time.Date(2024, 1, 3, 4, 0, 0, 0, time.UTC)
+		}, []int{3, 1}},
+	}
+
+	for name, c := range cases {
+		filter := base
+		c.modify(&filter)
+
+		entries, err := s.Find(ctx, store.Query{Filter: filter})
+		require.NoError(t, err, "Find should not return an error")
+		assert.Equal(t, c.want, ids(entries), "Filter %q should select the expected tasks", name)
+	}
+}
+
+func testPages(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+	require.NoError(t, s.Insert(ctx, task(7, "acme", 7, pb.TaskType_CRON, "report", false, "2024-01-03T05:00:00Z")))
+
+	var seen []int
+	q := store.Query{Filter: query.Filter{Organization: "acme", ProjectID: 7}, Limit: 2}
+	for page := 0; page < 10; page++ {
+		entries, err := s.Find(ctx, q)
+		require.NoError(t, err, "Find should not return an error")
+		if len(entries) == 0 {
+			break
+		}
+		seen = append(seen, ids(entries)...)
+		cursor := entries[len(entries)-1].Cursor()
+		q.After = &cursor
+	}
+
+	assert.Equal(t, []int{7, 4, 3, 2, 1}, seen, "Paging should visit every entry once, in order")
+}
+
+func testEach(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+
+	stop := errors.New("stop")
+	var visited []int
+	err := s.Each(ctx, store.Query{Filter: query.Filter{Organization: "acme"}}, func(entry store.Entry) error {
+		visited = append(visited, entry.Task.ID)
+		if len(visited) == 3 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop, "Each should return the callback error")
+	assert.Equal(t, []int{4, 3, 5}, visited, "Each should stop at the first callback error")
+}
+
+func testLatest(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+	require.NoError(t, s.InsertMany(ctx, []map[string]interface{}{
+		task(1, "acme", 7, pb.TaskType_CRON, "nightly-backup", true, "2024-01-04T02:15:00Z"),
+		task(1, "acme", 7, pb.TaskType_CRON, "nightly-backup", false, "2024-01-02T02:15:00Z"),
+		task(1, "globex", 7, pb.TaskType_CRON, "backup", false, "2024-01-01T02:15:00Z"),
+	}))
+
+	entries, err := s.Latest(ctx, query.Filter{From: time.Date(2024, 1, 3, 2, 40, 0, 0, time.UTC)})
+	require.NoError(t, err, "Latest should not return an error")
+	latest := make(map[string]model.Task)
+	for _, entry := range entries {
+		latest[fmt.Sprintf("%s/%d/%d", entry.Task.Organization, entry.Task.ProjectID, entry.Task.ID)] = entry.Task
+	}
+	assert.Len(t, latest, 5, "Latest should return one entry per task within the filter")
+	assert.Len(t, entries, 5, "Latest should not repeat a task")
+	assert.True(t, latest["acme/7/1"].Disabled, "The newest snapshot of a task should win")
+	assert.NotContains(t, latest, "globex/7/1", "Snapshots outside the filter should be ignored")
+	assert.NotContains(t, latest, "acme/7/2", "Tasks last stored before From should be ignored")
+}
+
+func testCountAndDelete(t *testing.T, s store.LogStore) {
+	ctx := context.Background()
+	seed(t, s)
+
+	count, err := s.Count(ctx, query.Filter{Organization: "acme"})
+	require.NoError(t, err, "Count should not return an error")
+	assert.Equal(t, int64(5), count, "Count should apply the filter")
+
+	deleted, err := s.Delete(ctx, query.Filter{Organization: "acme", ProjectID: 7, Types: []pb.TaskType{pb.TaskType_CRON}})
+	require.NoError(t, err, "Delete should not return an error")
+	assert.Equal(t, int64(3), deleted, "Delete should report how many entries it removed")
+
+	entries, err := s.Find(ctx, store.Query{})
+	require.NoError(t, err, "Find should not return an error")
+	assert.Equal(t, []int{6, 5, 2}, ids(entries), "Only non-matching entries should remain")
+}
@@ -0,0 +1,115 @@
+package tail
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+)
+
+// Hub fans stored entries out to live subscribers. Publishing never blocks: a
+// subscriber whose buffer is full misses the task and has it counted as
+// dropped instead, so one slow client cannot stall ingestion.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+type Subscription struct {
+	hub     *Hub
+	filter  query.Filter
+	entries chan store.Entry
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscription]struct{})}
+}
+
+func (h *Hub) Subscribe(filter query.Filter, buffer int) *Subscription {
+	subscription := &Subscription{
+		hub:     h,
+		filter:  filter,
+		entries: make(chan store.Entry, buffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(subscription.entries)
+		return subscription
+	}
+	h.subscribers[subscription] = struct{}{}
+	return subscription
+}
+
+func (h *Hub) Publish(entries []store.Entry) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for subscription := range h.subscribers {
+		for _, entry := range entries {
+			if !subscription.filter.Match(entry.Task) {
+				continue
+			}
+			select {
+			case subscription.entries <- entry:
+			default:
+				subscription.dropped.Add(1)
+			}
+		}
+	}
+}
+
+// PublishDocuments publishes freshly stored documents, skipping any that do
+// not decode into a Task.
+func (h *Hub) PublishDocuments(documents []map[string]interface{}) {
+	entries := make([]store.Entry, 0, len(documents))
+	for _, document := range documents {
+		entry, err := store.EntryFromDocument(document)
+		if err != nil {
+			log.Printf("Failed to decode document for tailing: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	h.Publish(entries)
+}
+
+// Close ends every subscription; their entry channels are closed once drained.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscription := range h.subscribers {
+		delete(h.subscribers, subscription)
+		close(subscription.entries)
+	}
+}
+
+func (s *Subscription) Entries() <-chan store.Entry {
+	return s.entries
+}
+
+// TakeDropped returns how many tasks were dropped since the last call.
+func (s *Subscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+
+		if _, ok := s.hub.subscribers[s]; ok {
+			delete(s.hub.subscribers, s)
+			close(s.entries)
+		}
+	})
+}
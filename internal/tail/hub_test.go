@@ -0,0 +1,35 @@
+package tail
+
+import (
+	"testing"
+
+	"github.com/bondzai/logger/internal/model"
+	"github.com/bondzai/logger/internal/query"
+	"github.com/bondzai/logger/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHubDropsForSlowSubscribers tests that a full subscriber buffer counts
+// dropped tasks instead of blocking the publisher.
+func TestHubDropsForSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	subscription := hub.Subscribe(query.Filter{Organization: "acme", ProjectID: 7}, 2)
+	defer subscription.Close()
+
+	hub.Publish([]store.Entry{
+		{ID: "1", Task: model.Task{ID: 1, Organization: "acme", ProjectID: 7}},
+		{ID: "2", Task: model.Task{ID: 2, Organization: "other", ProjectID: 7}},
+		{ID: "3", Task: model.Task{ID: 3, Organization: "acme", ProjectID: 7}},
+		{ID: "4", Task: model.Task{ID: 4, Organization: "acme", ProjectID: 7}},
+		{ID: "5", Task: model.Task{ID: 5, Organization: "acme", ProjectID: 7}},
+	})
+
+	assert.Equal(t, "1", (<-subscription.Entries()).ID, "First matching task should be delivered")
+	assert.Equal(t, "3", (<-subscription.Entries()).ID, "Second matching task should be delivered")
+	assert.Equal(t, int64(2), subscription.TakeDropped(), "Tasks beyond the buffer should be counted as dropped")
+	assert.Equal(t, int64(0), subscription.TakeDropped(), "Dropped count should reset once taken")
+
+	hub.Close()
+	_, ok := <-subscription.Entries()
+	assert.False(t, ok, "Closing the hub should end the subscription")
+}
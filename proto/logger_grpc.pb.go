@@ -19,8 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	AlertLogger_HealthCheck_FullMethodName = "/AlertLogger/HealthCheck"
-	AlertLogger_GetLogs_FullMethodName     = "/AlertLogger/GetLogs"
+	AlertLogger_HealthCheck_FullMethodName      = "/AlertLogger/HealthCheck"
+	AlertLogger_GetLogs_FullMethodName          = "/AlertLogger/GetLogs"
+	AlertLogger_TailLogs_FullMethodName         = "/AlertLogger/TailLogs"
+	AlertLogger_StreamLogs_FullMethodName       = "/AlertLogger/StreamLogs"
+	AlertLogger_GetExecutions_FullMethodName    = "/AlertLogger/GetExecutions"
+	AlertLogger_ListOverdueTasks_FullMethodName = "/AlertLogger/ListOverdueTasks"
+	AlertLogger_ExportLogs_FullMethodName       = "/AlertLogger/ExportLogs"
 )
 
 // AlertLoggerClient is the client API for AlertLogger service.
@@ -29,6 +34,11 @@ const (
 type AlertLoggerClient interface {
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
 	GetLogs(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*TaskResponse, error)
+	TailLogs(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (AlertLogger_TailLogsClient, error)
+	StreamLogs(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (AlertLogger_StreamLogsClient, error)
+	GetExecutions(ctx context.Context, in *ExecutionRequest, opts ...grpc.CallOption) (*ExecutionResponse, error)
+	ListOverdueTasks(ctx context.Context, in *OverdueRequest, opts ...grpc.CallOption) (*OverdueResponse, error)
+	ExportLogs(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (AlertLogger_ExportLogsClient, error)
 }
 
 type alertLoggerClient struct {
@@ -57,12 +67,131 @@ func (c *alertLoggerClient) GetLogs(ctx context.Context, in *TaskRequest, opts .
 	return out, nil
 }
 
+func (c *alertLoggerClient) TailLogs(ctx context.Context, in *TailRequest, opts ...grpc.CallOption) (AlertLogger_TailLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AlertLogger_ServiceDesc.Streams[0], AlertLogger_TailLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &alertLoggerTailLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AlertLogger_TailLogsClient interface {
+	Recv() (*TailResponse, error)
+	grpc.ClientStream
+}
+
+type alertLoggerTailLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *alertLoggerTailLogsClient) Recv() (*TailResponse, error) {
+	m := new(TailResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *alertLoggerClient) StreamLogs(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (AlertLogger_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AlertLogger_ServiceDesc.Streams[1], AlertLogger_StreamLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &alertLoggerStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AlertLogger_StreamLogsClient interface {
+	Recv() (*TaskResponse, error)
+	grpc.ClientStream
+}
+
+type alertLoggerStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *alertLoggerStreamLogsClient) Recv() (*TaskResponse, error) {
+	m := new(TaskResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *alertLoggerClient) GetExecutions(ctx context.Context, in *ExecutionRequest, opts ...grpc.CallOption) (*ExecutionResponse, error) {
+	out := new(ExecutionResponse)
+	err := c.cc.Invoke(ctx, AlertLogger_GetExecutions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertLoggerClient) ListOverdueTasks(ctx context.Context, in *OverdueRequest, opts ...grpc.CallOption) (*OverdueResponse, error) {
+	out := new(OverdueResponse)
+	err := c.cc.Invoke(ctx, AlertLogger_ListOverdueTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertLoggerClient) ExportLogs(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (AlertLogger_ExportLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AlertLogger_ServiceDesc.Streams[2], AlertLogger_ExportLogs_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &alertLoggerExportLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AlertLogger_ExportLogsClient interface {
+	Recv() (*ExportChunk, error)
+	grpc.ClientStream
+}
+
+type alertLoggerExportLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *alertLoggerExportLogsClient) Recv() (*ExportChunk, error) {
+	m := new(ExportChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // AlertLoggerServer is the server API for AlertLogger service.
 // All implementations must embed UnimplementedAlertLoggerServer
 // for forward compatibility
 type AlertLoggerServer interface {
 	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
 	GetLogs(context.Context, *TaskRequest) (*TaskResponse, error)
+	TailLogs(*TailRequest, AlertLogger_TailLogsServer) error
+	StreamLogs(*TaskRequest, AlertLogger_StreamLogsServer) error
+	GetExecutions(context.Context, *ExecutionRequest) (*ExecutionResponse, error)
+	ListOverdueTasks(context.Context, *OverdueRequest) (*OverdueResponse, error)
+	ExportLogs(*ExportRequest, AlertLogger_ExportLogsServer) error
 	mustEmbedUnimplementedAlertLoggerServer()
 }
 
@@ -76,6 +205,21 @@ func (UnimplementedAlertLoggerServer) HealthCheck(context.Context, *HealthCheckR
 func (UnimplementedAlertLoggerServer) GetLogs(context.Context, *TaskRequest) (*TaskResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetLogs not implemented")
 }
+func (UnimplementedAlertLoggerServer) TailLogs(*TailRequest, AlertLogger_TailLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method TailLogs not implemented")
+}
+func (UnimplementedAlertLoggerServer) StreamLogs(*TaskRequest, AlertLogger_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedAlertLoggerServer) GetExecutions(context.Context, *ExecutionRequest) (*ExecutionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExecutions not implemented")
+}
+func (UnimplementedAlertLoggerServer) ListOverdueTasks(context.Context, *OverdueRequest) (*OverdueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOverdueTasks not implemented")
+}
+func (UnimplementedAlertLoggerServer) ExportLogs(*ExportRequest, AlertLogger_ExportLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExportLogs not implemented")
+}
 func (UnimplementedAlertLoggerServer) mustEmbedUnimplementedAlertLoggerServer() {}
 
 // UnsafeAlertLoggerServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +269,105 @@ func _AlertLogger_GetLogs_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AlertLogger_TailLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TailRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AlertLoggerServer).TailLogs(m, &alertLoggerTailLogsServer{stream})
+}
+
+type AlertLogger_TailLogsServer interface {
+	Send(*TailResponse) error
+	grpc.ServerStream
+}
+
+type alertLoggerTailLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *alertLoggerTailLogsServer) Send(m *TailResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AlertLogger_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TaskRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AlertLoggerServer).StreamLogs(m, &alertLoggerStreamLogsServer{stream})
+}
+
+type AlertLogger_StreamLogsServer interface {
+	Send(*TaskResponse) error
+	grpc.ServerStream
+}
+
+type alertLoggerStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *alertLoggerStreamLogsServer) Send(m *TaskResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AlertLogger_GetExecutions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecutionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertLoggerServer).GetExecutions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertLogger_GetExecutions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertLoggerServer).GetExecutions(ctx, req.(*ExecutionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertLogger_ListOverdueTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OverdueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertLoggerServer).ListOverdueTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertLogger_ListOverdueTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertLoggerServer).ListOverdueTasks(ctx, req.(*OverdueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertLogger_ExportLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AlertLoggerServer).ExportLogs(m, &alertLoggerExportLogsServer{stream})
+}
+
+type AlertLogger_ExportLogsServer interface {
+	Send(*ExportChunk) error
+	grpc.ServerStream
+}
+
+type alertLoggerExportLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *alertLoggerExportLogsServer) Send(m *ExportChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // AlertLogger_ServiceDesc is the grpc.ServiceDesc for AlertLogger service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,7 +383,31 @@ var AlertLogger_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetLogs",
 			Handler:    _AlertLogger_GetLogs_Handler,
 		},
+		{
+			MethodName: "GetExecutions",
+			Handler:    _AlertLogger_GetExecutions_Handler,
+		},
+		{
+			MethodName: "ListOverdueTasks",
+			Handler:    _AlertLogger_ListOverdueTasks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TailLogs",
+			Handler:       _AlertLogger_TailLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _AlertLogger_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ExportLogs",
+			Handler:       _AlertLogger_ExportLogs_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/logger.proto",
 }
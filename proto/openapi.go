@@ -0,0 +1,9 @@
+package proto
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI document of the REST gateway, generated from
+// logger.proto and logger_http.yaml.
+//
+//go:embed logger.swagger.json
+var OpenAPI []byte